@@ -4,10 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
-	"sync"
+	"text/tabwriter"
 
 	"github.com/devimteam/microgen/generator"
+	"github.com/devimteam/microgen/generator/template"
+	"github.com/devimteam/microgen/generator/write_strategy"
 	"github.com/devimteam/microgen/util"
 	"github.com/vetcher/godecl/types"
 )
@@ -16,74 +20,241 @@ const Version = generator.Version
 
 var (
 	flagFileName  = flag.String("file", "service.go", "Name of file where described interface definition")
+	flagPackages  = flag.String("pkg", "", "Generate for all interfaces in packages, e.g. ./... or ./svc. Overrides -file and -out")
 	flagOutputDir = flag.String("out", ".", "Output directory")
 	flagHelp      = flag.Bool("help", false, "Show help")
 	flagForce     = flag.Bool("force", false, "Overwrite all files, as it generates for the first time")
+	flagDryRun    = flag.Bool("dry-run", false, "List files, which would be created, appended, merged, removed or skipped, without writing them")
+	flagDiff      = flag.Bool("diff", false, "Print unified diff between files on disk and generated code, without writing them")
+	flagCheck     = flag.Bool("check", false, "Exit with non-zero code if generated files are out of date, without writing them")
+	flagJobs      = flag.Int("jobs", runtime.NumCPU(), "Number of files, which are generated at the same time")
+	flagLayout    = flag.String("layout", "", "Layout of generated files: "+strings.Join(template.LayoutNames(), ", ")+". Overrides config, legacy by default")
+	flagConfig    = flag.String("config", "", "Path to config file. By default "+generator.ConfigFileName+" is searched from directory of source file upwards")
+	flagListTags  = flag.Bool("list-tags", false, "Print all registered tags with their descriptions")
 )
 
-func init() {
-	flag.Parse()
-}
-
 func main() {
+	flag.Parse()
 	fmt.Println("@microgen", Version)
 	if *flagHelp || *flagFileName == "" {
 		flag.Usage()
 		os.Exit(0)
 	}
-
-	info, err := util.ParseFile(*flagFileName)
-	if err != nil {
-		fmt.Println("fatal:", err)
-		os.Exit(1)
+	if *flagListTags {
+		listTags()
+		return
 	}
 
-	i := findInterface(info)
-	if i == nil {
-		fmt.Println("fatal: could not find interface with @microgen tag")
-		os.Exit(1)
+	if flag.Arg(0) == "clean" {
+		// Flags after command are not parsed by flag.Parse.
+		flag.CommandLine.Parse(flag.Args()[1:])
+		clean()
+		return
 	}
 
-	if err := generator.ValidateInterface(i); err != nil {
-		fmt.Println("validation:", err)
+	if _, err := template.LayoutByName(*flagLayout); err != nil {
+		fmt.Println("fatal:", err)
 		os.Exit(1)
 	}
 
-	units, err := generator.ListTemplatesForGen(i, *flagForce, info.Name, *flagOutputDir, *flagFileName)
+	var sources []source
+	var err error
+	if *flagPackages != "" {
+		sources, err = findPackageSources(*flagPackages)
+	} else {
+		sources, err = findFileSources(*flagFileName, *flagOutputDir)
+	}
 	if err != nil {
 		fmt.Println("fatal:", err)
 		os.Exit(1)
 	}
-	var wg sync.WaitGroup
-	wg.Add(len(units))
-	for _, x := range units {
-		unit := x
-		go func() {
-			defer wg.Done()
-			err := unit.Generate()
-			if err != nil && err != generator.EmptyStrategyError {
+	if len(sources) == 0 {
+		fmt.Println("fatal: could not find interface with @microgen tag")
+		os.Exit(1)
+	}
+
+	configs := newConfigLoader(*flagConfig)
+	var units []*generator.GenerationUnit
+	for _, pkg := range groupByPackage(sources) {
+		var ifaces []*types.Interface
+		for _, src := range pkg {
+			if err := generator.ValidateInterface(src.iface); err != nil {
+				fmt.Println("validation:", err)
+				os.Exit(1)
+			}
+			ifaces = append(ifaces, src.iface)
+		}
+		// Services of package are generated to one directory with one config.
+		config, err := configs.forSource(pkg[0].filename)
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		layout, err := template.LayoutByName(config.Layout)
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		if err := generator.ValidatePackageInterfaces(ifaces, layout); err != nil {
+			fmt.Println("validation:", err)
+			os.Exit(1)
+		}
+		for _, src := range pkg {
+			config, err := configs.forSource(src.filename)
+			if err != nil {
+				fmt.Println("fatal:", err)
+				os.Exit(1)
+			}
+			u, err := generator.ListTemplatesForGen(src.iface, *flagForce, src.file.Name, src.outDir, src.filename, namespace(pkg, src), config)
+			if err != nil {
+				fmt.Println("fatal:", err)
+				os.Exit(1)
+			}
+			units = append(units, u...)
+		}
+	}
+
+	// Print what generation would do with every file.
+	if *flagDryRun || *flagDiff {
+		var plans []*write_strategy.Plan
+		for _, unit := range units {
+			plan, err := unit.Plan()
+			if err != nil {
 				fmt.Println("fatal:", err)
+				os.Exit(1)
 			}
-		}()
+			plans = append(plans, plan)
+		}
+		manifestPlans, err := generator.PlanManifests(units, plans)
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		printPlans(append(plans, manifestPlans...))
+		return
+	}
+
+	if *flagCheck {
+		changed, err := generator.Check(units)
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		if len(changed) > 0 {
+			fmt.Println("Generated files are out of date:")
+			for _, plan := range changed {
+				switch plan.Action {
+				case write_strategy.AppendFileMark:
+					fmt.Println("missing declarations:", plan.Path)
+				case write_strategy.MergeFileMark:
+					fmt.Println("outdated declarations:", plan.Path)
+				default:
+					fmt.Println("stale:", plan.Path)
+				}
+			}
+			os.Exit(1)
+		}
+		fmt.Println("All generated files are up to date")
+		return
+	}
+
+	errs := generator.GenerateAll(units, *flagJobs)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Println("fatal:", err)
+		}
+		fmt.Println("Generation failed, files were not changed")
+		os.Exit(1)
 	}
-	wg.Wait()
 	fmt.Println("All files successfully generated")
 }
 
-func findInterface(file *types.File) *types.Interface {
-	for i := range file.Interfaces {
-		if docsContainMicrogenTag(file.Interfaces[i].Docs) {
-			return &file.Interfaces[i]
+// Removes generated files, which were not modified, from output directory or directories of packages.
+func clean() {
+	dirs := []string{*flagOutputDir}
+	if *flagPackages != "" {
+		var err error
+		dirs, err = packageDirs(*flagPackages)
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+	}
+	var plans []*write_strategy.Plan
+	for _, dir := range dirs {
+		// Not every package of pattern is generated.
+		if _, err := os.Stat(filepath.Join(dir, generator.ManifestFileName)); os.IsNotExist(err) && *flagPackages != "" {
+			continue
+		}
+		dirPlans, err := generator.Clean(dir)
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
 		}
+		plans = append(plans, dirPlans...)
+	}
+	if *flagDryRun || *flagDiff {
+		printPlans(plans)
+		return
+	}
+	if err := generator.Apply(plans); err != nil {
+		fmt.Println("fatal:", err)
+		fmt.Println("Clean failed, files were not changed")
+		os.Exit(1)
 	}
-	return nil
+	fmt.Println("Generated files successfully removed")
 }
 
-func docsContainMicrogenTag(strs []string) bool {
-	for _, str := range strs {
-		if strings.HasPrefix(str, generator.TagMark+generator.MicrogenMainTag) {
-			return true
+// Prints registered tags with their descriptions, required and conflicting tags.
+func listTags() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, tag := range template.RegisteredTags() {
+		description := tag.Description
+		if len(tag.Requires) > 0 {
+			description += " (requires: " + strings.Join(tag.Requires, ", ") + ")"
 		}
+		if len(tag.Conflicts) > 0 {
+			description += " (conflicts: " + strings.Join(tag.Conflicts, ", ") + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", tag.Name, description)
+	}
+	w.Flush()
+}
+
+func printPlans(plans []*write_strategy.Plan) {
+	for _, plan := range plans {
+		if *flagDryRun {
+			fmt.Println(plan.Action, plan.Path)
+		}
+		if *flagDiff {
+			path := diffPath(diffBase(), plan.Path)
+			fmt.Print(util.UnifiedDiff("a/"+path, "b/"+path, plan.Current, plan.Result))
+		}
+	}
+}
+
+// Returns directory, which paths in diff are relative to: output directory
+// or working directory, when several packages are generated.
+func diffBase() string {
+	if *flagPackages != "" {
+		return "."
+	}
+	return *flagOutputDir
+}
+
+// Returns slash-separated path relative to base, so diff can be applied with `git apply` or `patch -p1` from base.
+func diffPath(base, path string) string {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(absBase, absPath)
+	if err != nil {
+		return filepath.ToSlash(path)
 	}
-	return false
+	return filepath.ToSlash(rel)
 }
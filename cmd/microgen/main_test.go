@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffPath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		base, path, want string
+	}{
+		{".", filepath.Join(wd, "transport/grpc/server.go"), "transport/grpc/server.go"},
+		{".", "endpoints.go", "endpoints.go"},
+		{"gen", "gen/endpoints.go", "endpoints.go"},
+		{filepath.Join(wd, "gen"), filepath.Join(wd, "service.go"), "../service.go"},
+	} {
+		if got := diffPath(c.base, c.path); got != c.want {
+			t.Errorf("diffPath(%q, %q) = %q, expected %q", c.base, c.path, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/devimteam/microgen/generator"
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+// Interface with @microgen tag and file, where it was declared.
+type source struct {
+	file     *types.File
+	filename string
+	iface    *types.Interface
+	outDir   string
+}
+
+// Finds all interfaces with @microgen tag in file.
+func findFileSources(filename, outDir string) ([]source, error) {
+	file, err := util.ParseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var sources []source
+	for _, iface := range findInterfaces(file) {
+		sources = append(sources, source{
+			file:     file,
+			filename: filename,
+			iface:    iface,
+			outDir:   outDir,
+		})
+	}
+	return sources, nil
+}
+
+// Finds all interfaces with @microgen tag in packages, that match pattern.
+// Files are generated to directory of package.
+func findPackageSources(pattern string) ([]source, error) {
+	dirs, err := packageDirs(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var sources []source
+	for _, dir := range dirs {
+		filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			if strings.HasSuffix(filename, "_test.go") {
+				continue
+			}
+			found, err := findFileSources(filename, dir)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, found...)
+		}
+	}
+	return sources, nil
+}
+
+// Returns directories, that match pattern.
+// Pattern is a directory or a directory with all subdirectories, when ends with `...`.
+// Hidden directories, vendor and testdata are skipped.
+func packageDirs(pattern string) ([]string, error) {
+	if !strings.HasSuffix(pattern, "...") {
+		return []string{filepath.Clean(pattern)}, nil
+	}
+	root := filepath.Clean(strings.TrimSuffix(pattern, "..."))
+	var dirs []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs, err
+}
+
+// Groups sources by output directory, keeping order of sources.
+func groupByPackage(sources []source) [][]source {
+	var groups [][]source
+	index := make(map[string]int)
+	for _, src := range sources {
+		dir := filepath.Clean(src.outDir)
+		i, ok := index[dir]
+		if !ok {
+			i = len(groups)
+			index[dir] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], src)
+	}
+	return groups
+}
+
+// Returns namespace of service, so services of one package do not share files.
+// Namespace is empty, when package contains one service.
+//
+//		StringService -> string_service
+//
+func namespace(pkg []source, src source) string {
+	if len(pkg) < 2 {
+		return ""
+	}
+	return util.ToSnakeCase(src.iface.Name)
+}
+
+func findInterfaces(file *types.File) (ifaces []*types.Interface) {
+	for i := range file.Interfaces {
+		if docsContainMicrogenTag(file.Interfaces[i].Docs) {
+			ifaces = append(ifaces, &file.Interfaces[i])
+		}
+	}
+	return
+}
+
+func docsContainMicrogenTag(strs []string) bool {
+	for _, str := range strs {
+		if strings.HasPrefix(str, generator.TagMark+generator.MicrogenMainTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Loads configs of sources and applies flags to them.
+// Every config file is loaded once.
+type configLoader struct {
+	filename string
+	loaded   map[string]*generator.Config
+}
+
+// Creates loader, which uses provided config file or searches config for every source.
+func newConfigLoader(filename string) *configLoader {
+	return &configLoader{
+		filename: filename,
+		loaded:   make(map[string]*generator.Config),
+	}
+}
+
+func (l *configLoader) forSource(sourceFile string) (*generator.Config, error) {
+	filename := l.filename
+	if filename == "" {
+		var err error
+		filename, err = generator.FindConfig(filepath.Dir(sourceFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config, ok := l.loaded[filename]; ok {
+		return config, nil
+	}
+	config := &generator.Config{}
+	if filename != "" {
+		var err error
+		config, err = generator.LoadConfig(filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Flags override config.
+	if *flagLayout != "" {
+		config.Layout = *flagLayout
+	}
+	l.loaded[filename] = config
+	return config, nil
+}
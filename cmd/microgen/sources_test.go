@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const servicesSource = `package svc
+
+import "context"
+
+// @microgen http
+type StringService interface {
+	Uppercase(ctx context.Context, s string) (res string, err error)
+}
+
+// Not generated.
+type Helper interface {
+	Help() error
+}
+
+// @microgen grpc
+type CountService interface {
+	Count(ctx context.Context, s string) (n int, err error)
+}
+`
+
+func writeSources(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		filename := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func sourceNames(sources []source) string {
+	var names []string
+	for _, src := range sources {
+		names = append(names, src.iface.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestFindFileSources(t *testing.T) {
+	root, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeSources(t, root, map[string]string{"service.go": servicesSource})
+
+	sources, err := findFileSources(filepath.Join(root, "service.go"), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := sourceNames(sources); names != "StringService,CountService" {
+		t.Fatalf("unexpected interfaces: %s", names)
+	}
+	groups := groupByPackage(sources)
+	if len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("interfaces of one file are not grouped: %v", groups)
+	}
+	if ns := namespace(groups[0], sources[1]); ns != "count_service" {
+		t.Errorf("unexpected namespace %q", ns)
+	}
+	if ns := namespace(groups[0][:1], sources[0]); ns != "" {
+		t.Errorf("single service has namespace %q", ns)
+	}
+}
+
+func TestFindPackageSources(t *testing.T) {
+	root, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	single := strings.Replace(servicesSource, "// @microgen grpc", "// Not generated.", 1)
+	writeSources(t, root, map[string]string{
+		"svc/service.go":          servicesSource,
+		"svc/service_test.go":     single,
+		"other/service.go":        single,
+		"other/nested/doc.go":     "package nested\n",
+		"vendor/lib/service.go":   single,
+		"testdata/service.go":     single,
+		".hidden/service.go":      single,
+		"_ignored/svc/service.go": single,
+	})
+
+	dirs, err := packageDirs(root + "/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rel []string
+	for _, dir := range dirs {
+		r, _ := filepath.Rel(root, dir)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	if got := strings.Join(rel, ","); got != ".,other,other/nested,svc" {
+		t.Errorf("unexpected package directories: %s", got)
+	}
+	if dirs, _ := packageDirs(filepath.Join(root, "svc")); len(dirs) != 1 || dirs[0] != filepath.Join(root, "svc") {
+		t.Errorf("unexpected directories of single package: %v", dirs)
+	}
+
+	sources, err := findPackageSources(root + "/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := sourceNames(sources); names != "StringService,StringService,CountService" {
+		t.Fatalf("unexpected interfaces: %s", names)
+	}
+	groups := groupByPackage(sources)
+	if len(groups) != 2 || len(groups[0]) != 1 || len(groups[1]) != 2 {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+	if groups[0][0].outDir != filepath.Join(root, "other") || groups[1][0].outDir != filepath.Join(root, "svc") {
+		t.Errorf("sources are not generated to directories of packages: %s, %s", groups[0][0].outDir, groups[1][0].outDir)
+	}
+	if ns := namespace(groups[0], groups[0][0]); ns != "" {
+		t.Errorf("single service of package has namespace %q", ns)
+	}
+}
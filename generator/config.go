@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/devimteam/microgen/generator/template"
+	"gopkg.in/yaml.v2"
+)
+
+const ConfigFileName = "microgen.yaml"
+
+// Project configuration, loaded from microgen.yaml.
+// Values of config override values of interface tags, flags override config.
+//
+//		layout: addsvc
+//		packages:
+//		  endpoint: svcendpoint
+//		listen:
+//		  http: :9000
+//		  grpc: :9001
+//		json-naming: camel
+//		force:
+//		  - grpc-client
+//		openapi:
+//		  format: json
+//		  version: 2.1.0
+//
+type Config struct {
+	// Name of layout, see template.LayoutNames.
+	Layout string `yaml:"layout"`
+	// Names of generated packages by package kind, e.g. `endpoint` or `grpc-transport`.
+	Packages map[string]string `yaml:"packages"`
+	// Default addresses of servers in generated main.
+	Listen struct {
+		HTTP string `yaml:"http"`
+		GRPC string `yaml:"grpc"`
+		// Address of server, which exposes prometheus metrics.
+		Metrics string `yaml:"metrics"`
+	} `yaml:"listen"`
+	// Naming of json fields of exchanges: `snake` or `camel`.
+	JSONNaming string `yaml:"json-naming"`
+	// Tags, which files are always overwritten. Replaces @force tag, when set.
+	Force []string `yaml:"force"`
+	// OpenAPI document options.
+	OpenAPI struct {
+		// Format of document: `yaml` or `json`.
+		Format string `yaml:"format"`
+		// Version of API.
+		Version string `yaml:"version"`
+	} `yaml:"openapi"`
+}
+
+// Searches config file in dir and its parents.
+// Returns empty string, when config is not found.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		filename := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Reads and validates config file.
+func LoadConfig(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	if _, err := template.LayoutByName(c.Layout); err != nil {
+		return err
+	}
+	if _, err := c.packageNames(); err != nil {
+		return err
+	}
+	switch c.JSONNaming {
+	case "", template.JSONNamingSnake, template.JSONNamingCamel:
+	default:
+		return fmt.Errorf("unknown json-naming %s, expected %s or %s", c.JSONNaming, template.JSONNamingSnake, template.JSONNamingCamel)
+	}
+	switch c.OpenAPI.Format {
+	case "", template.OpenAPIFormatYAML, template.OpenAPIFormatJSON:
+	default:
+		return fmt.Errorf("unknown openapi format %s, expected %s or %s", c.OpenAPI.Format, template.OpenAPIFormatYAML, template.OpenAPIFormatJSON)
+	}
+	return nil
+}
+
+func (c *Config) packageNames() (map[template.PackageKind]string, error) {
+	names := make(map[template.PackageKind]string)
+	for key, name := range c.Packages {
+		kind, ok := template.PackageKindByName(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown package %s", key)
+		}
+		names[kind] = name
+	}
+	return names, nil
+}
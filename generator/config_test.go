@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/devimteam/microgen/generator/template"
+)
+
+func TestFindConfig(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	writeTestFile(t, filepath.Join(root, ConfigFileName), "layout: addsvc\n")
+	writeTestFile(t, filepath.Join(root, "svc", "nested", "service.go"), "package nested\n")
+
+	filename, err := FindConfig(filepath.Join(root, "svc", "nested"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != filepath.Join(root, ConfigFileName) {
+		t.Errorf("%s != %s", filename, filepath.Join(root, ConfigFileName))
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	filename := filepath.Join(root, ConfigFileName)
+	writeTestFile(t, filename, `layout: addsvc
+packages:
+  endpoint: svcendpoint
+listen:
+  http: :9000
+json-naming: camel
+force: [grpc-client]
+`)
+
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Layout != template.AddsvcLayoutName {
+		t.Errorf("layout: %s", config.Layout)
+	}
+	if config.Listen.HTTP != ":9000" || config.Listen.GRPC != "" {
+		t.Errorf("listen: %v", config.Listen)
+	}
+	if config.JSONNaming != template.JSONNamingCamel {
+		t.Errorf("json-naming: %s", config.JSONNaming)
+	}
+	if len(config.Force) != 1 || config.Force[0] != "grpc-client" {
+		t.Errorf("force: %v", config.Force)
+	}
+	names, err := config.packageNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names[template.EndpointPackage] != "svcendpoint" {
+		t.Errorf("packages: %v", names)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	tests := []string{
+		"layout: flat\n",
+		"packages:\n  endpoints: svcendpoint\n",
+		"json-naming: kebab\n",
+		"unknown: field\n",
+	}
+	for i, test := range tests {
+		filename := filepath.Join(root, ConfigFileName)
+		writeTestFile(t, filename, test)
+		if _, err := LoadConfig(filename); err == nil {
+			t.Errorf("%d: expected error for %q", i+1, test)
+		}
+	}
+}
@@ -21,17 +21,45 @@ const (
 	MiddlewareTag        = template.MiddlewareTag
 	LoggingMiddlewareTag = template.LoggingMiddlewareTag
 	RecoverMiddlewareTag = template.RecoverMiddlewareTag
+	MetricsMiddlewareTag = template.MetricsMiddlewareTag
+	TracingMiddlewareTag = template.TracingMiddlewareTag
 	HttpTag              = template.HttpTag
 	HttpServerTag        = template.HttpServerTag
 	HttpClientTag        = template.HttpClientTag
+	HttpConvertersTag    = template.HttpConvertersTag
 	GrpcTag              = template.GrpcTag
 	GrpcServerTag        = template.GrpcServerTag
 	GrpcClientTag        = template.GrpcClientTag
+	GrpcConvertersTag    = template.GrpcConvertersTag
 	MainTag              = template.MainTag
+	ProtoTag             = template.ProtoTag
+	OpenAPITag           = template.OpenAPITag
+	MockTag              = template.MockTag
+
+	defaultHTTPAddr = ":8080"
+	defaultGRPCAddr = ":8081"
+	// Default port of prometheus exporters.
+	defaultMetricsAddr = ":9100"
+
+	defaultOpenAPIVersion = "1.0.0"
 )
 
-func ListTemplatesForGen(iface *types.Interface, force bool, importPackageName, absOutPath, sourcePath string) (units []*generationUnit, err error) {
-	importPackagePath, err := resolvePackagePath(absOutPath)
+// Returns generation units for all templates, requested by interface tags.
+// Namespace should be provided, when package contains several services.
+// Config may be nil, flags should be already applied to it.
+func ListTemplatesForGen(iface *types.Interface, force bool, importPackageName, absOutPath, sourcePath, namespace string, config *Config) (units []*GenerationUnit, err error) {
+	if config == nil {
+		config = &Config{}
+	}
+	layout, err := template.LayoutByName(config.Layout)
+	if err != nil {
+		return nil, err
+	}
+	packageNames, err := config.packageNames()
+	if err != nil {
+		return nil, err
+	}
+	outPackagePath, err := resolvePackagePath(absOutPath)
 	if err != nil {
 		return nil, err
 	}
@@ -39,15 +67,35 @@ func ListTemplatesForGen(iface *types.Interface, force bool, importPackageName,
 	if err != nil {
 		return nil, err
 	}
+	importPackagePath, err := resolvePackagePath(filepath.Dir(absSourcePath))
+	if err != nil {
+		return nil, err
+	}
 	info := &template.GenerationInfo{
 		ServiceImportPackageName: importPackageName,
 		ServiceImportPath:        importPackagePath,
 		Force:                    force,
 		Iface:                    iface,
 		AbsOutPath:               absOutPath,
+		OutImportPath:            outPackagePath,
+		Layout:                   layout,
 		SourceFilePath:           absSourcePath,
+		Namespace:                namespace,
 		ProtobufPackage:          fetchMetaInfo(TagMark+ProtobufTag, iface.Docs),
 		GRPCRegAddr:              fetchMetaInfo(TagMark+GRPCRegAddr, iface.Docs),
+		PackageNames:             packageNames,
+		HTTPAddr:                 defaultString(config.Listen.HTTP, defaultHTTPAddr),
+		GRPCAddr:                 defaultString(config.Listen.GRPC, defaultGRPCAddr),
+		MetricsAddr:              defaultString(config.Listen.Metrics, defaultMetricsAddr),
+		JSONNaming:               defaultString(config.JSONNaming, template.JSONNamingSnake),
+		ForceTags:                config.Force,
+		OpenAPIFormat:            defaultString(config.OpenAPI.Format, template.OpenAPIFormatYAML),
+		OpenAPIVersion:           defaultString(config.OpenAPI.Version, defaultOpenAPIVersion),
+		Types:                    template.TypeResolverFor(filepath.Dir(absSourcePath)),
+	}
+	// Config overrides tags.
+	if info.ForceTags == nil {
+		info.ForceTags = util.FetchTags(iface.Docs, TagMark+template.ForceTag)
 	}
 	stubSvc, err := NewGenUnit(template.NewStubInterfaceTemplate(info), absOutPath)
 	if err != nil {
@@ -61,27 +109,50 @@ func ListTemplatesForGen(iface *types.Interface, force bool, importPackageName,
 	if err != nil {
 		return nil, err
 	}
-	units = append(units, stubSvc, exch, endp)
+	errs, err := NewGenUnit(template.NewErrorsTemplate(info), absOutPath)
+	if err != nil {
+		return nil, err
+	}
+	units = append(units, stubSvc, exch, endp, errs)
+	for _, unit := range units {
+		unit.iface, unit.sourcePath, unit.force = iface, absSourcePath, force
+	}
 
 	genTags := util.FetchTags(iface.Docs, TagMark+MicrogenMainTag)
-	fmt.Println("Tags:", strings.Join(genTags, ", "))
-	for _, tag := range genTags {
-		templates := tagToTemplate(tag, info)
-		if templates == nil {
-			fmt.Printf("Warning! unexpected tag %s\n", tag)
-			continue
-		}
-		for _, t := range templates {
+	fmt.Printf("%s tags: %s\n", iface.Name, strings.Join(genTags, ", "))
+	tags, unknown, err := template.ResolveTags(genTags)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range unknown {
+		fmt.Printf("Warning! unexpected tag %s\n", tag)
+	}
+	// Templates, which are required by several tags, are generated once.
+	generated := make(map[string]bool)
+	for _, tag := range tags {
+		for _, t := range tag.Templates(info) {
+			if generated[t.DefaultPath()] {
+				continue
+			}
+			generated[t.DefaultPath()] = true
 			unit, err := NewGenUnit(t, absOutPath)
 			if err != nil {
 				return nil, err
 			}
+			unit.iface, unit.tag, unit.sourcePath, unit.force = iface, tag.Name, absSourcePath, force
 			units = append(units, unit)
 		}
 	}
 	return units, nil
 }
 
+func defaultString(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
 // Fetch information from slice of comments (docs).
 // Returns appendix of first comment which has tag as prefix.
 func fetchMetaInfo(tag string, comments []string) string {
@@ -93,70 +164,51 @@ func fetchMetaInfo(tag string, comments []string) string {
 	return ""
 }
 
-func tagToTemplate(tag string, info *template.GenerationInfo) (tmpls []template.Template) {
-	switch tag {
-	case MiddlewareTag:
-		return append(tmpls, template.NewMiddlewareTemplate(info))
-	case LoggingMiddlewareTag:
-		return append(tmpls, template.NewLoggingTemplate(info))
-	case GrpcTag:
-		return append(tmpls,
-			template.NewGRPCClientTemplate(info),
-			template.NewGRPCServerTemplate(info),
-			template.NewGRPCEndpointConverterTemplate(info),
-			template.NewStubGRPCTypeConverterTemplate(info),
-		)
-	case GrpcClientTag:
-		return append(tmpls,
-			template.NewGRPCClientTemplate(info),
-			template.NewGRPCEndpointConverterTemplate(info),
-			template.NewStubGRPCTypeConverterTemplate(info),
-		)
-	case GrpcServerTag:
-		return append(tmpls,
-			template.NewGRPCServerTemplate(info),
-			template.NewGRPCEndpointConverterTemplate(info),
-			template.NewStubGRPCTypeConverterTemplate(info),
-		)
-	case HttpTag:
-		return append(tmpls,
-			template.NewHttpServerTemplate(info),
-			template.NewHttpClientTemplate(info),
-			template.NewHttpConverterTemplate(info),
-		)
-	case HttpServerTag:
-		return append(tmpls,
-			template.NewHttpServerTemplate(info),
-			template.NewHttpConverterTemplate(info),
-		)
-	case HttpClientTag:
-		return append(tmpls,
-			template.NewHttpClientTemplate(info),
-			template.NewHttpConverterTemplate(info),
-		)
-	case RecoverMiddlewareTag:
-		return append(tmpls, template.NewRecoverTemplate(info))
-	case MainTag:
-		return append(tmpls, template.NewMainTemplate(info))
-	}
-	return nil
-}
-
+// Resolves import path of package, which will be located in outPath.
+// Nearest go.mod file and its local replace directives have priority.
+// When out directory is outside of any module, local replace directives of
+// module in working directory are checked, and then GOPATH is used.
 func resolvePackagePath(outPath string) (string, error) {
-	gopath := os.Getenv("GOPATH")
-	if gopath == "" {
-		return "", fmt.Errorf("GOPATH is empty")
+	absOutPath, err := filepath.Abs(outPath)
+	if err != nil {
+		return "", err
 	}
 
-	absOutPath, err := filepath.Abs(outPath)
+	mod, err := util.FindGoMod(absOutPath)
 	if err != nil {
 		return "", err
 	}
+	if mod != nil {
+		if path, ok := mod.ImportPath(absOutPath); ok {
+			return path, nil
+		}
+	}
 
-	gopathSrc := filepath.Join(gopath, "src")
-	if !strings.HasPrefix(absOutPath, gopathSrc) {
-		return "", fmt.Errorf("path not in GOPATH")
+	if wd, err := os.Getwd(); err == nil {
+		mod, err := util.FindGoMod(wd)
+		if err != nil {
+			return "", err
+		}
+		if mod != nil {
+			if path, ok := mod.ReplacedImportPath(absOutPath); ok {
+				return path, nil
+			}
+		}
 	}
 
-	return absOutPath[len(gopathSrc)+1:], nil
+	return resolveGopathPackagePath(absOutPath)
+}
+
+func resolveGopathPackagePath(absOutPath string) (string, error) {
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		return "", fmt.Errorf("go.mod not found and GOPATH is empty")
+	}
+
+	for _, dir := range filepath.SplitList(gopath) {
+		if path, ok := util.GopathImportPath(dir, absOutPath); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("go.mod not found and path not in GOPATH")
 }
@@ -0,0 +1,188 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, filename, content string) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Resolve symlinks (e.g. /tmp on macOS) to compare paths with working directory.
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func chdir(t *testing.T, dir string) func() {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return func() { os.Chdir(wd) }
+}
+
+func setenv(t *testing.T, key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestResolvePackagePathModule(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	writeTestFile(t, filepath.Join(root, "go.mod"), "// Comment\nmodule \"github.com/user/project\" // trailing\n\ngo 1.11\n")
+	defer chdir(t, root)()
+
+	tests := []struct {
+		dir  string
+		path string
+	}{
+		{".", "github.com/user/project"},
+		{"svc", "github.com/user/project/svc"},
+		{"pkg/stringsvc", "github.com/user/project/pkg/stringsvc"},
+	}
+	for _, test := range tests {
+		path, err := resolvePackagePath(filepath.Join(root, test.dir))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.dir, err)
+			continue
+		}
+		if path != test.path {
+			t.Errorf("%s: %s != %s", test.dir, path, test.path)
+		}
+	}
+}
+
+func TestResolvePackagePathNestedModule(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module github.com/user/project\n")
+	writeTestFile(t, filepath.Join(root, "api", "go.mod"), "module github.com/user/api\n")
+
+	tests := []struct {
+		dir  string
+		path string
+	}{
+		{"svc", "github.com/user/project/svc"},
+		{"api", "github.com/user/api"},
+		{"api/stringsvc", "github.com/user/api/stringsvc"},
+	}
+	for _, test := range tests {
+		path, err := resolvePackagePath(filepath.Join(root, test.dir))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.dir, err)
+			continue
+		}
+		if path != test.path {
+			t.Errorf("%s: %s != %s", test.dir, path, test.path)
+		}
+	}
+}
+
+func TestResolvePackagePathReplace(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	writeTestFile(t, filepath.Join(root, "app", "go.mod"), `module github.com/user/app
+
+require github.com/user/lib v1.0.0
+
+replace github.com/user/lib => ../lib
+
+replace (
+	github.com/user/proto v0.1.0 => ./internal/proto
+	github.com/user/remote => github.com/fork/remote v1.0.0
+)
+`)
+	defer chdir(t, filepath.Join(root, "app"))()
+
+	tests := []struct {
+		dir  string
+		path string
+	}{
+		{"app/svc", "github.com/user/app/svc"},
+		{"app/internal/proto", "github.com/user/proto"},
+		{"app/internal/proto/stringsvc", "github.com/user/proto/stringsvc"},
+		{"lib/stringsvc", "github.com/user/lib/stringsvc"},
+	}
+	for _, test := range tests {
+		path, err := resolvePackagePath(filepath.Join(root, test.dir))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.dir, err)
+			continue
+		}
+		if path != test.path {
+			t.Errorf("%s: %s != %s", test.dir, path, test.path)
+		}
+	}
+}
+
+func TestResolvePackagePathVendor(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	writeTestFile(t, filepath.Join(root, "module", "go.mod"), "module github.com/user/project\n")
+	defer setenv(t, "GOPATH", filepath.Join(root, "gopath"))()
+
+	tests := []struct {
+		dir  string
+		path string
+	}{
+		{"module/vendor/github.com/user/lib", "github.com/user/lib"},
+		{"gopath/src/github.com/user/project/vendor/github.com/user/lib/svc", "github.com/user/lib/svc"},
+	}
+	for _, test := range tests {
+		path, err := resolvePackagePath(filepath.Join(root, test.dir))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.dir, err)
+			continue
+		}
+		if path != test.path {
+			t.Errorf("%s: %s != %s", test.dir, path, test.path)
+		}
+	}
+}
+
+func TestResolvePackagePathGopath(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	defer chdir(t, root)()
+	defer setenv(t, "GOPATH", filepath.Join(root, "first")+string(filepath.ListSeparator)+filepath.Join(root, "second"))()
+
+	path, err := resolvePackagePath(filepath.Join(root, "second", "src", "github.com", "user", "svc"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "github.com/user/svc" {
+		t.Errorf("%s != github.com/user/svc", path)
+	}
+
+	if _, err := resolvePackagePath(filepath.Join(root, "outside")); err == nil {
+		t.Error("expected error for path outside GOPATH")
+	}
+}
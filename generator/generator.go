@@ -3,9 +3,16 @@ package generator
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/devimteam/microgen/generator/template"
 	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/vetcher/godecl/types"
 )
 
 const Version = template.Version
@@ -15,18 +22,33 @@ var (
 	EmptyStrategyError = errors.New("empty strategy")
 )
 
-type Generator interface {
-	Generate() error
+// Error of generation unit with information about its template.
+type UnitError struct {
+	Template string
+	Path     string
+	Err      error
 }
 
-type generationUnit struct {
+func (e *UnitError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Template, e.Path, e.Err)
+}
+
+// GenerationUnit renders one template with its write strategy.
+type GenerationUnit struct {
 	template template.Template
 
 	writeStrategy write_strategy.Strategy
 	absOutPath    string
+
+	// Interface, tag and source file, which are listed in manifest.
+	iface      *types.Interface
+	tag        string
+	sourcePath string
+	// Overwrite files, which were modified after generation.
+	force bool
 }
 
-func NewGenUnit(tmpl template.Template, outPath string) (*generationUnit, error) {
+func NewGenUnit(tmpl template.Template, outPath string) (*GenerationUnit, error) {
 	err := tmpl.Prepare()
 	if err != nil {
 		return nil, fmt.Errorf("%s: prepare error: %v", tmpl.DefaultPath(), err)
@@ -35,24 +57,185 @@ func NewGenUnit(tmpl template.Template, outPath string) (*generationUnit, error)
 	if err != nil {
 		return nil, err
 	}
-	return &generationUnit{
+	return &GenerationUnit{
 		template:      tmpl,
 		absOutPath:    outPath,
 		writeStrategy: strategy,
 	}, nil
 }
 
-func (g *generationUnit) Generate() error {
+// Returns what Generate would do, without touching file system.
+// Units without strategy are planned to be skipped.
+// Files, which were modified after generation, are overwritten only with force.
+func (g *GenerationUnit) Plan() (*write_strategy.Plan, error) {
 	if g.template == nil {
-		return EmptyTemplateError
+		return nil, EmptyTemplateError
 	}
 	if g.writeStrategy == nil {
-		return EmptyStrategyError
+		return &write_strategy.Plan{
+			Action: write_strategy.SkipFileMark,
+			Path:   filepath.Join(g.absOutPath, g.template.DefaultPath()),
+		}, nil
+	}
+	plan, err := g.writeStrategy.Plan(g.template.Render())
+	if err != nil {
+		return nil, fmt.Errorf("plan error: %v", err)
+	}
+	if plan != nil && plan.Action == write_strategy.NewFileMark && !g.force && write_strategy.IsModified(plan.Current) {
+		return nil, fmt.Errorf("%s was modified after generation, use -force to overwrite it", plan.Path)
+	}
+	return plan, nil
+}
+
+// Regenerates all units in memory and returns plans of files, which are out of date.
+// Units, which refuse to overwrite existing files, are compared as if they overwrite them.
+func Check(units []*GenerationUnit) ([]*write_strategy.Plan, error) {
+	memory := write_strategy.NewMemory()
+	for _, unit := range units {
+		if unit.template == nil {
+			return nil, EmptyTemplateError
+		}
+		strategy := unit.writeStrategy
+		if strategy == nil {
+			strategy = write_strategy.NewCreateFileStrategy(unit.absOutPath, unit.template.DefaultPath())
+		}
+		if _, err := memory.Wrap(strategy).Plan(unit.template.Render()); err != nil {
+			return nil, fmt.Errorf("%s: %v", unit.template.DefaultPath(), err)
+		}
+	}
+	return memory.Changed(), nil
+}
+
+// Plans all units, running no more than jobs units at the same time.
+// Returns plans in order of units or errors of all failed units, ordered by default paths of their templates.
+func PlanAll(units []*GenerationUnit, jobs int) ([]*write_strategy.Plan, []error) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	plans := make([]*write_strategy.Plan, len(units))
+	errs := make([]*UnitError, len(units))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	wg.Add(len(units))
+	for i := range units {
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			plan, err := units[i].Plan()
+			if err != nil {
+				errs[i] = units[i].error(err)
+				return
+			}
+			plans[i] = plan
+		}(i)
+	}
+	wg.Wait()
+
+	var failed []*UnitError
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == 0 {
+		return plans, nil
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		return failed[i].Path < failed[j].Path
+	})
+	result := make([]error, len(failed))
+	for i := range failed {
+		result[i] = failed[i]
+	}
+	return nil, result
+}
+
+// Generates all units in one transaction: files are staged in temporary directory
+// and written only when every unit succeeded. When writing fails, original files are restored.
+// Manifests of output directories are updated in the same transaction, files, which
+// are not generated anymore, are removed. Modified files, which are overwritten, are saved to `.orig` files.
+// Returns errors of all failed units, ordered by default paths of their templates.
+func GenerateAll(units []*GenerationUnit, jobs int) []error {
+	plans, errs := PlanAll(units, jobs)
+	if len(errs) > 0 {
+		return errs
+	}
+	tx, err := write_strategy.NewTransaction()
+	if err != nil {
+		return []error{err}
+	}
+	defer tx.Close()
+	for _, plan := range plans {
+		if err := tx.Stage(plan); err != nil {
+			return []error{err}
+		}
+	}
+	for _, plan := range plans {
+		if plan.Action != write_strategy.NewFileMark || !write_strategy.IsModified(plan.Current) {
+			continue
+		}
+		backup, err := backupPlan(plan)
+		if err != nil {
+			return []error{err}
+		}
+		if err := tx.Stage(backup); err != nil {
+			return []error{err}
+		}
 	}
-	code := g.template.Render()
-	err := g.writeStrategy.Write(code)
+	// Checksums in manifest are calculated from combined content of staged files.
+	manifestPlans, err := planManifests(units, plans, func(plan *write_strategy.Plan) []byte {
+		if content, ok := tx.Staged(plan.Path); ok {
+			return content
+		}
+		return plan.Result
+	})
 	if err != nil {
-		return fmt.Errorf("write error: %v", err)
+		return []error{err}
 	}
+	for _, plan := range manifestPlans {
+		if err := tx.Stage(plan); err != nil {
+			return []error{err}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return []error{err}
+	}
+	removeEmptyDirs(manifestPlans)
 	return nil
 }
+
+// Plans saving of current content of file to file with `.orig` suffix.
+func backupPlan(plan *write_strategy.Plan) (*write_strategy.Plan, error) {
+	path := plan.Path + ".orig"
+	current, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &write_strategy.Plan{
+		Action:  write_strategy.NewFileMark,
+		Path:    path,
+		Current: current,
+		Result:  plan.Current,
+	}, nil
+}
+
+func (g *GenerationUnit) error(err error) *UnitError {
+	if g.template == nil {
+		return &UnitError{Err: err}
+	}
+	return &UnitError{
+		Template: templateName(g.template),
+		Path:     g.template.DefaultPath(),
+		Err:      err,
+	}
+}
+
+// Returns short name of template, e.g. `endpoints` for *template.endpointsTemplate.
+func templateName(t template.Template) string {
+	name := fmt.Sprintf("%T", t)
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "Template")
+}
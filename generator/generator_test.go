@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devimteam/microgen/generator/write_strategy"
+)
+
+type testTemplate struct {
+	path string
+	err  error
+}
+
+func (t testTemplate) Prepare() error      { return nil }
+func (t testTemplate) DefaultPath() string { return t.path }
+func (t testTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return testStrategy{err: t.err}, nil
+}
+func (t testTemplate) Render() write_strategy.Renderer { return testRenderer{} }
+
+type testRenderer struct{}
+
+func (testRenderer) Render(io.Writer) error { return nil }
+
+type testStrategy struct {
+	err error
+}
+
+func (s testStrategy) Plan(write_strategy.Renderer) (*write_strategy.Plan, error) {
+	return nil, s.err
+}
+
+func TestPlanAllErrorsOrder(t *testing.T) {
+	var units []*GenerationUnit
+	for _, tmpl := range []testTemplate{
+		{path: "./transport/http/server.go", err: errors.New("server")},
+		{path: "./endpoints.go"},
+		{path: "./middleware/logging.go", err: errors.New("logging")},
+		{path: "./exchanges.go", err: errors.New("exchanges")},
+	} {
+		unit, err := NewGenUnit(tmpl, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		units = append(units, unit)
+	}
+
+	for _, jobs := range []int{0, 1, 4} {
+		_, errs := PlanAll(units, jobs)
+		expected := []string{
+			"test (./exchanges.go): plan error: exchanges",
+			"test (./middleware/logging.go): plan error: logging",
+			"test (./transport/http/server.go): plan error: server",
+		}
+		if len(errs) != len(expected) {
+			t.Fatalf("jobs %d: expected %d errors, got %v", jobs, len(expected), errs)
+		}
+		for i := range errs {
+			if errs[i].Error() != expected[i] {
+				t.Errorf("jobs %d: %s != %s", jobs, errs[i], expected[i])
+			}
+		}
+	}
+}
+
+type headerRenderer struct{}
+
+func (headerRenderer) Render(w io.Writer) error {
+	_, err := io.WriteString(w, "// This file was automatically generated by \"microgen\" utility.\npackage svc\n")
+	return err
+}
+
+type headerTemplate struct{}
+
+func (headerTemplate) Prepare() error      { return nil }
+func (headerTemplate) DefaultPath() string { return "./endpoints.go" }
+func (headerTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return nil, nil
+}
+func (headerTemplate) Render() write_strategy.Renderer { return headerRenderer{} }
+
+func TestModifiedFileIsNotOverwritten(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	unit := &GenerationUnit{
+		template:      headerTemplate{},
+		absOutPath:    dir,
+		writeStrategy: write_strategy.NewCreateFileStrategy(dir, "./endpoints.go"),
+	}
+	if errs := GenerateAll([]*GenerationUnit{unit}, 1); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	path := filepath.Join(dir, "endpoints.go")
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := append(content, "\nvar patched = true\n"...)
+	if err := ioutil.WriteFile(path, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := GenerateAll([]*GenerationUnit{unit}, 1)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "was modified after generation, use -force to overwrite it") {
+		t.Fatalf("expected modification error, got %v", errs)
+	}
+
+	unit.force = true
+	if errs := GenerateAll([]*GenerationUnit{unit}, 1); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if backup, err := ioutil.ReadFile(path + ".orig"); err != nil || string(backup) != string(edited) {
+		t.Errorf("modified file is not backed up: %v\n%s", err, backup)
+	}
+	if content, _ := ioutil.ReadFile(path); strings.Contains(string(content), "patched") {
+		t.Errorf("modified file is not overwritten:\n%s", content)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	unit := &GenerationUnit{
+		template:      headerTemplate{},
+		absOutPath:    dir,
+		writeStrategy: write_strategy.NewCreateFileStrategy(dir, "./endpoints.go"),
+	}
+	// Unit without strategy is compared as if it overwrites file.
+	skipped := &GenerationUnit{template: headerTemplate{}, absOutPath: dir}
+	path := filepath.Join(dir, "endpoints.go")
+	check := func(state string, expected int) {
+		for _, u := range []*GenerationUnit{unit, skipped} {
+			changed, err := Check([]*GenerationUnit{u})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changed) != expected {
+				t.Errorf("%s file: expected %d changed plans, got %v", state, expected, changed)
+				continue
+			}
+			if expected > 0 && changed[0].Path != path {
+				t.Errorf("%s file: unexpected path %s", state, changed[0].Path)
+			}
+		}
+	}
+
+	check("missing", 1)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("check writes files: %v", err)
+	}
+	if errs := GenerateAll([]*GenerationUnit{unit}, 1); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	check("up-to-date", 0)
+	if err := ioutil.WriteFile(path, []byte("package svc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("stale", 1)
+}
@@ -0,0 +1,378 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/vetcher/godecl/types"
+)
+
+// Name of manifest, which lists files, generated to output directory.
+const ManifestFileName = ".microgen.lock"
+
+// Manifest lists files, which were produced by microgen in output directory.
+type Manifest struct {
+	// Version of microgen, which wrote manifest.
+	Version string          `json:"version"`
+	Files   []*ManifestFile `json:"files"`
+}
+
+// File, which was produced by template for interface.
+type ManifestFile struct {
+	// Path, relative to directory of manifest, with forward slashes.
+	Path     string `json:"path"`
+	Template string `json:"template"`
+	// Tag, which requested template. Empty for files, which are always generated.
+	Tag       string `json:"tag,omitempty"`
+	Interface string `json:"interface"`
+	// Hash of interface declaration, file was generated for.
+	InterfaceHash string `json:"interface_hash"`
+	// Hash of content, written by microgen. Empty, when file contains code of user,
+	// e.g. merged converters, such files are never removed.
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Plans manifests of output directories of units and removal of files, which are listed
+// in manifests, but are not generated for the same interfaces anymore, e.g. after tag was removed.
+// Plans should be in order of units. Modified files are not removed, warning is printed instead.
+func PlanManifests(units []*GenerationUnit, plans []*write_strategy.Plan) ([]*write_strategy.Plan, error) {
+	return planManifests(units, plans, func(plan *write_strategy.Plan) []byte {
+		return plan.Result
+	})
+}
+
+// Plans removal of files, listed in manifest of directory, which were not modified after generation.
+// Modified files are kept in manifest, manifest is removed, when it lists nothing.
+func Clean(dir string) ([]*write_strategy.Plan, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	manifest, current, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("%s: %s not found", dir, ManifestFileName)
+	}
+	kept := &Manifest{Version: manifest.Version}
+	var removed []*ManifestFile
+	for _, f := range manifest.Files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if f.Checksum == "" || f.Checksum != checksum(content) {
+			fmt.Printf("Warning! %s was modified, it is kept\n", f.Path)
+			kept.Files = append(kept.Files, f)
+			continue
+		}
+		removed = append(removed, f)
+	}
+	plans, err := planRemoval(dir, removed, kept)
+	if err != nil {
+		return nil, err
+	}
+	plan := &write_strategy.Plan{
+		Action:  write_strategy.RemoveFileMark,
+		Path:    filepath.Join(dir, ManifestFileName),
+		Current: current,
+	}
+	if len(kept.Files) > 0 {
+		plan, err = planManifest(dir, kept, current)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(plans, plan), nil
+}
+
+// Stages and commits plans in one transaction.
+// Directories, which become empty after removal of files, are removed too.
+func Apply(plans []*write_strategy.Plan) error {
+	tx, err := write_strategy.NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+	for _, plan := range plans {
+		if err := tx.Stage(plan); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	removeEmptyDirs(plans)
+	return nil
+}
+
+func planManifests(units []*GenerationUnit, plans []*write_strategy.Plan, result func(*write_strategy.Plan) []byte) ([]*write_strategy.Plan, error) {
+	var dirs []string
+	byDir := make(map[string][]int)
+	for i, unit := range units {
+		dir, err := filepath.Abs(unit.absOutPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], i)
+	}
+
+	var manifestPlans []*write_strategy.Plan
+	for _, dir := range dirs {
+		old, current, err := readManifest(dir)
+		if err != nil {
+			return nil, err
+		}
+		manifest := &Manifest{Version: Version}
+		// Interfaces, which are generated now.
+		generated := make(map[string]bool)
+		listed := make(map[string]bool)
+		for _, i := range byDir[dir] {
+			unit, plan := units[i], plans[i]
+			if unit.iface == nil || plan == nil {
+				continue
+			}
+			generated[unit.iface.Name] = true
+			path, err := filepath.Abs(plan.Path)
+			if err != nil {
+				return nil, err
+			}
+			// Source file is written by user.
+			if path == unit.sourcePath {
+				continue
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			if listed[manifestKey(rel, unit.iface.Name)] {
+				continue
+			}
+			entry := &ManifestFile{
+				Path:          rel,
+				Template:      templateName(unit.template),
+				Tag:           unit.tag,
+				Interface:     unit.iface.Name,
+				InterfaceHash: interfaceHash(unit.iface),
+			}
+			prev := old.find(rel, unit.iface.Name)
+			switch plan.Action {
+			case write_strategy.NewFileMark:
+				entry.Checksum = checksum(result(plan))
+			case write_strategy.SkipFileMark:
+				// File was not written, so it is listed only when it was generated before.
+				// Units without strategy do not read current content, so existence is checked here.
+				if prev == nil {
+					continue
+				}
+				if plan.Current == nil {
+					if _, err := os.Stat(path); os.IsNotExist(err) {
+						continue
+					} else if err != nil {
+						return nil, err
+					}
+				}
+				entry.Checksum = prev.Checksum
+			default:
+				// Merged file contains only generated code, when it was not modified after previous generation.
+				if prev != nil && prev.Checksum != "" && prev.Checksum == checksum(plan.Current) {
+					entry.Checksum = checksum(result(plan))
+				}
+			}
+			listed[manifestKey(rel, unit.iface.Name)] = true
+			manifest.Files = append(manifest.Files, entry)
+		}
+
+		var orphans []*ManifestFile
+		for _, f := range old.Files {
+			if listed[manifestKey(f.Path, f.Interface)] {
+				continue
+			}
+			// Interface may be declared in other file, which is not generated now.
+			if !generated[f.Interface] {
+				manifest.Files = append(manifest.Files, f)
+				continue
+			}
+			content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			if f.Checksum == "" || f.Checksum != checksum(content) {
+				fmt.Printf("Warning! %s is not generated for %s anymore, but it was modified, remove it manually\n", f.Path, f.Interface)
+				manifest.Files = append(manifest.Files, f)
+				continue
+			}
+			orphans = append(orphans, f)
+		}
+		removals, err := planRemoval(dir, orphans, manifest)
+		if err != nil {
+			return nil, err
+		}
+		plan, err := planManifest(dir, manifest, current)
+		if err != nil {
+			return nil, err
+		}
+		manifestPlans = append(append(manifestPlans, removals...), plan)
+	}
+	return manifestPlans, nil
+}
+
+// Plans removal of files, which are not listed in kept manifest.
+func planRemoval(dir string, files []*ManifestFile, kept *Manifest) ([]*write_strategy.Plan, error) {
+	keptPaths := make(map[string]bool)
+	for _, f := range kept.Files {
+		keptPaths[f.Path] = true
+	}
+	var plans []*write_strategy.Plan
+	for _, f := range files {
+		if keptPaths[f.Path] {
+			continue
+		}
+		keptPaths[f.Path] = true
+		path := filepath.Join(dir, filepath.FromSlash(f.Path))
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, &write_strategy.Plan{
+			Action:  write_strategy.RemoveFileMark,
+			Path:    path,
+			Current: content,
+		})
+	}
+	return plans, nil
+}
+
+func planManifest(dir string, manifest *Manifest, current []byte) (*write_strategy.Plan, error) {
+	result, err := manifest.marshal()
+	if err != nil {
+		return nil, err
+	}
+	plan := &write_strategy.Plan{
+		Action:  write_strategy.NewFileMark,
+		Path:    filepath.Join(dir, ManifestFileName),
+		Current: current,
+		Result:  result,
+	}
+	if !plan.Changed() {
+		plan.Action = write_strategy.SkipFileMark
+	}
+	return plan, nil
+}
+
+// Reads manifest from directory. Returns empty manifest and nil content, when it does not exist.
+func readManifest(dir string) (*Manifest, []byte, error) {
+	manifest := &Manifest{}
+	content, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if os.IsNotExist(err) {
+		return manifest, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filepath.Join(dir, ManifestFileName), err)
+	}
+	return manifest, content, nil
+}
+
+func (m *Manifest) marshal() ([]byte, error) {
+	sort.SliceStable(m.Files, func(i, j int) bool {
+		if m.Files[i].Path != m.Files[j].Path {
+			return m.Files[i].Path < m.Files[j].Path
+		}
+		return m.Files[i].Interface < m.Files[j].Interface
+	})
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+func (m *Manifest) find(path, iface string) *ManifestFile {
+	for _, f := range m.Files {
+		if f.Path == path && f.Interface == iface {
+			return f
+		}
+	}
+	return nil
+}
+
+func manifestKey(path, iface string) string {
+	return path + "\x00" + iface
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns hash of interface declaration: its name, tags and signatures of methods.
+func interfaceHash(iface *types.Interface) string {
+	h := sha256.New()
+	fmt.Fprintln(h, iface.Name)
+	for _, doc := range iface.Docs {
+		fmt.Fprintln(h, doc)
+	}
+	for _, fn := range iface.Methods {
+		fmt.Fprint(h, fn.Name, "(")
+		for _, arg := range fn.Args {
+			fmt.Fprint(h, arg.Name, " ", arg.Type.String(), ",")
+		}
+		fmt.Fprint(h, ")(")
+		for _, res := range fn.Results {
+			fmt.Fprint(h, res.Name, " ", res.Type.String(), ",")
+		}
+		fmt.Fprintln(h, ")")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Removes directories, which became empty after removal of files, up to directory of manifest.
+func removeEmptyDirs(plans []*write_strategy.Plan) {
+	var roots []string
+	for _, plan := range plans {
+		if filepath.Base(plan.Path) == ManifestFileName {
+			if root, err := filepath.Abs(filepath.Dir(plan.Path)); err == nil {
+				roots = append(roots, root)
+			}
+		}
+	}
+	inRoot := func(dir string) bool {
+		for _, root := range roots {
+			if strings.HasPrefix(dir, root+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, plan := range plans {
+		if plan.Action != write_strategy.RemoveFileMark {
+			continue
+		}
+		path, err := filepath.Abs(plan.Path)
+		if err != nil {
+			continue
+		}
+		// Directory with other files can not be removed.
+		for dir := filepath.Dir(path); inRoot(dir) && os.Remove(dir) == nil; dir = filepath.Dir(dir) {
+		}
+	}
+}
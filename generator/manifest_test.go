@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/vetcher/godecl/types"
+)
+
+func TestManifestRemovesOrphans(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	iface := &types.Interface{Base: types.Base{Name: "StringService"}}
+	unit := func(path, tag string) *GenerationUnit {
+		return &GenerationUnit{template: testTemplate{path: path}, absOutPath: dir, iface: iface, tag: tag}
+	}
+	plan := func(path, content string) *write_strategy.Plan {
+		return &write_strategy.Plan{Action: write_strategy.NewFileMark, Path: filepath.Join(dir, path), Result: []byte(content)}
+	}
+	generate := func(units []*GenerationUnit, plans []*write_strategy.Plan) []*write_strategy.Plan {
+		manifestPlans, err := PlanManifests(units, plans)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Apply(append(plans, manifestPlans...)); err != nil {
+			t.Fatal(err)
+		}
+		return manifestPlans
+	}
+
+	generate(
+		[]*GenerationUnit{unit("endpoints.go", ""), unit("transport/grpc/server.go", "grpc"), unit("transport/grpc/client.go", "grpc")},
+		[]*write_strategy.Plan{plan("endpoints.go", "endpoints"), plan("transport/grpc/server.go", "server"), plan("transport/grpc/client.go", "client")},
+	)
+	// Modified file is kept after grpc tag is removed.
+	if err := ioutil.WriteFile(filepath.Join(dir, "transport/grpc/client.go"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifestPlans := generate(
+		[]*GenerationUnit{unit("endpoints.go", "")},
+		[]*write_strategy.Plan{plan("endpoints.go", "endpoints")},
+	)
+	if len(manifestPlans) != 2 || manifestPlans[0].Action != write_strategy.RemoveFileMark {
+		t.Fatalf("expected removal of server and manifest update, got %v", manifestPlans)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "transport/grpc/server.go")); !os.IsNotExist(err) {
+		t.Errorf("orphan is not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "transport/grpc/client.go")); err != nil {
+		t.Errorf("modified orphan is removed: %v", err)
+	}
+
+	manifest, _, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 || manifest.Version != Version || manifest.find("transport/grpc/client.go", "StringService") == nil {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	plans, err := Clean(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(plans); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "endpoints.go")); !os.IsNotExist(err) {
+		t.Errorf("generated file is not cleaned: %v", err)
+	}
+	if manifest, _, _ := readManifest(dir); len(manifest.Files) != 1 {
+		t.Errorf("modified file is not kept in manifest: %+v", manifest)
+	}
+}
+
+func TestManifestKeepsSkippedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	iface := &types.Interface{Base: types.Base{Name: "StringService"}}
+	units := []*GenerationUnit{{template: testTemplate{path: "transport/http/server.go"}, absOutPath: dir, iface: iface, tag: "http"}}
+	path := filepath.Join(dir, "transport/http/server.go")
+	plans := []*write_strategy.Plan{{Action: write_strategy.NewFileMark, Path: path, Result: []byte("server")}}
+	manifestPlans, err := PlanManifests(units, plans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(append(plans, manifestPlans...)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Existing file is skipped by unit without strategy, its content is not read.
+	skipped := []*write_strategy.Plan{{Action: write_strategy.SkipFileMark, Path: path}}
+	manifestPlans, err = PlanManifests(units, skipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifestPlans) != 1 || manifestPlans[0].Action != write_strategy.SkipFileMark {
+		t.Fatalf("expected unchanged manifest, got %v", manifestPlans)
+	}
+}
@@ -1,9 +1,11 @@
 package template
 
 import (
+	"strconv"
 	"strings"
 
 	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
 	"github.com/devimteam/microgen/util"
 	"github.com/vetcher/godecl/types"
 )
@@ -15,6 +17,7 @@ const (
 	PackagePathTime               = "time"
 	PackagePathGoogleGRPC         = "google.golang.org/grpc"
 	PackagePathGoogleGRPCCodes    = "google.golang.org/grpc/codes"
+	PackagePathGoogleGRPCStatus   = "google.golang.org/grpc/status"
 	PackagePathNetContext         = "golang.org/x/net/context"
 	PackagePathGoKitTransportGRPC = "github.com/go-kit/kit/transport/grpc"
 	PackagePathHttp               = "net/http"
@@ -31,6 +34,21 @@ const (
 	PackagePathSyscall            = "syscall"
 	PackagePathErrors             = "errors"
 	PackagePathNet                = "net"
+	PackagePathGoKitMetrics       = "github.com/go-kit/kit/metrics"
+	PackagePathGoKitPrometheus    = "github.com/go-kit/kit/metrics/prometheus"
+	PackagePathPrometheus         = "github.com/prometheus/client_golang/prometheus"
+	PackagePathPrometheusHTTP     = "github.com/prometheus/client_golang/prometheus/promhttp"
+	PackagePathOpenTracing        = "github.com/opentracing/opentracing-go"
+	PackagePathOpenTracingExt     = "github.com/opentracing/opentracing-go/ext"
+	PackagePathGoKitOpenTracing   = "github.com/go-kit/kit/tracing/opentracing"
+	PackagePathGorillaMux         = "github.com/gorilla/mux"
+	PackagePathStrconv            = "strconv"
+	PackagePathCryptoRand         = "crypto/rand"
+	PackagePathSync               = "sync"
+	PackagePathTesting            = "testing"
+	PackagePathReflect            = "reflect"
+	PackagePathHttpTest           = "net/http/httptest"
+	PackagePathGRPCBufConn        = "google.golang.org/grpc/test/bufconn"
 
 	TagMark         = "// @"
 	MicrogenMainTag = "microgen"
@@ -44,20 +62,25 @@ const (
 	MiddlewareTag        = "middleware"
 	LoggingMiddlewareTag = "logging"
 	RecoverMiddlewareTag = "recover"
+	MetricsMiddlewareTag = "metrics"
+	TracingMiddlewareTag = "tracing"
 	HttpTag              = "http"
 	HttpServerTag        = "http-server"
 	HttpClientTag        = "http-client"
+	HttpConvertersTag    = "http-converters"
 	GrpcTag              = "grpc"
 	GrpcServerTag        = "grpc-server"
 	GrpcClientTag        = "grpc-client"
+	GrpcConvertersTag    = "grpc-converters"
 	MainTag              = "main"
+	ProtoTag             = "proto"
+	OpenAPITag           = "openapi"
+	MockTag              = "mock"
 )
 
-type WriteStrategyState int
-
 const (
-	FileStrat WriteStrategyState = iota + 1
-	AppendStrat
+	JSONNamingSnake = "snake"
+	JSONNamingCamel = "camel"
 )
 
 type GenerationInfo struct {
@@ -66,10 +89,35 @@ type GenerationInfo struct {
 	ServiceImportPath        string
 	Force                    bool
 	AbsOutPath               string
-	SourceFilePath           string
+	// Import path of output directory.
+	OutImportPath  string
+	SourceFilePath string
+	// Places generated files. Legacy layout is used, when it is nil.
+	Layout Layout
+	// Snake case name of service, when its package contains several services.
+	// Layout uses it to separate files of different services.
+	// Empty, when package contains only one service.
+	Namespace string
 
 	ProtobufPackage string
 	GRPCRegAddr     string
+
+	// Names of packages, which override names from layout.
+	PackageNames map[PackageKind]string
+	// Addresses of servers in generated main.
+	HTTPAddr    string
+	GRPCAddr    string
+	MetricsAddr string
+	// Naming of json fields of exchanges.
+	JSONNaming string
+	// Tags, which files are always overwritten.
+	ForceTags []string
+	// Format of OpenAPI document: yaml or json.
+	OpenAPIFormat string
+	// Version of API in OpenAPI document.
+	OpenAPIVersion string
+	// Full type information of service package and its dependencies. May be nil.
+	Types *TypeResolver
 }
 
 func (info GenerationInfo) Copy() *GenerationInfo {
@@ -79,13 +127,109 @@ func (info GenerationInfo) Copy() *GenerationInfo {
 		ServiceImportPackageName: info.ServiceImportPackageName,
 		ServiceImportPath:        info.ServiceImportPath,
 		AbsOutPath:               info.AbsOutPath,
+		OutImportPath:            info.OutImportPath,
+		Layout:                   info.Layout,
 		SourceFilePath:           info.SourceFilePath,
+		Namespace:                info.Namespace,
 
 		GRPCRegAddr:     info.GRPCRegAddr,
 		ProtobufPackage: info.ProtobufPackage,
+
+		PackageNames: info.PackageNames,
+		HTTPAddr:     info.HTTPAddr,
+		GRPCAddr:     info.GRPCAddr,
+		MetricsAddr:  info.MetricsAddr,
+		JSONNaming:   info.JSONNaming,
+		ForceTags:    info.ForceTags,
+
+		OpenAPIFormat:  info.OpenAPIFormat,
+		OpenAPIVersion: info.OpenAPIVersion,
+
+		Types: info.Types,
 	}
 }
 
+// Import of service package, which qualifies types, declared in it.
+func (info *GenerationInfo) serviceImport() *types.Import {
+	return &types.Import{
+		Base:    types.Base{Name: info.ServiceImportPackageName},
+		Package: info.ServiceImportPath,
+	}
+}
+
+// Returns copy of info, which interface has qualified types, declared in service package.
+// Files of other packages refer to these types with name of service package,
+// and jen omits qualifier in files of service package itself.
+//
+//		Count(ctx context.Context, comment *Comment) -> Count(ctx context.Context, comment *stringsvc.Comment)
+//
+func (info *GenerationInfo) qualified() *GenerationInfo {
+	q := info.Copy()
+	iface := *info.Iface
+	iface.Methods = qualifySignatures(info.Iface.Methods, info.serviceImport())
+	q.Iface = &iface
+	return q
+}
+
+// Returns copies of functions, which arguments and results are qualified with imp.
+func qualifySignatures(signatures []*types.Function, imp *types.Import) []*types.Function {
+	qualify := func(vars []types.Variable) []types.Variable {
+		qualified := make([]types.Variable, len(vars))
+		for i, v := range vars {
+			v.Type = qualifyType(v.Type, imp)
+			qualified[i] = v
+		}
+		return qualified
+	}
+	qualified := make([]*types.Function, len(signatures))
+	for i, signature := range signatures {
+		fn := *signature
+		fn.Args = qualify(signature.Args)
+		fn.Results = qualify(signature.Results)
+		qualified[i] = &fn
+	}
+	return qualified
+}
+
+var builtinTypes = []string{
+	"bool", "string", "error", "byte", "rune", "uintptr", "complex64", "complex128",
+	"int", "int8", "int16", "int32", "int64",
+	"uint", "uint8", "uint16", "uint32", "uint64",
+	"float32", "float64",
+}
+
+// Qualifies named types, which are declared in package of imp.
+//
+//		[]*Comment -> []*entity.Comment
+//
+func qualifyType(typ types.Type, imp *types.Import) types.Type {
+	switch f := typ.(type) {
+	case types.TName:
+		if util.IsInStringSlice(f.TypeName, builtinTypes) {
+			return f
+		}
+		return types.TImport{Import: imp, Next: f}
+	case types.TImport:
+		if f.Import == nil {
+			return qualifyType(f.Next, imp)
+		}
+	case types.TPointer:
+		f.Next = qualifyType(f.Next, imp)
+		return f
+	case types.TArray:
+		f.Next = qualifyType(f.Next, imp)
+		return f
+	case types.TEllipsis:
+		f.Next = qualifyType(f.Next, imp)
+		return f
+	case types.TMap:
+		f.Key = qualifyType(f.Key, imp)
+		f.Value = qualifyType(f.Value, imp)
+		return f
+	}
+	return typ
+}
+
 func structFieldName(field *types.Variable) *Statement {
 	return Id(util.ToUpperFirst(field.Name))
 }
@@ -133,16 +277,27 @@ func nameOfLastResultError(fn *types.Function) string {
 //
 //  	Visit *entity.Visit `json:"visit"`
 //
-func structField(field *types.Variable) *Statement {
+func structField(field *types.Variable, naming string) *Statement {
 	s := structFieldName(field)
 	s.Add(fieldType(field.Type, false))
-	s.Tag(map[string]string{"json": util.ToSnakeCase(field.Name)})
+	s.Tag(map[string]string{"json": jsonFieldName(field.Name, naming)})
 	if types.IsEllipsis(field.Type) {
 		s.Comment("This field was defined with ellipsis (...).")
 	}
 	return s
 }
 
+// Returns name of json field in requested naming style, snake case by default.
+//
+//		userID -> user_id or userID
+//
+func jsonFieldName(name, naming string) string {
+	if naming == JSONNamingCamel {
+		return util.ToLowerFirst(name)
+	}
+	return util.ToSnakeCase(name)
+}
+
 // Renders func params for definition.
 //
 //  	visit *entity.Visit, err error
@@ -209,6 +364,22 @@ func interfaceType(p *types.Interface) (code []Code) {
 	return
 }
 
+// Returns name of local variable, which does not clash with arguments and results of function.
+//
+//		fn -> fn1, when function has argument fn
+//
+func localName(name string, signature *types.Function) string {
+	taken := make(map[string]bool)
+	for _, field := range append(append([]types.Variable{}, signature.Args...), signature.Results...) {
+		taken[field.Name] = true
+	}
+	result := name
+	for i := 1; taken[result]; i++ {
+		result = name + strconv.Itoa(i)
+	}
+	return result
+}
+
 // Renders key/value pairs wrapped in Dict for provided fields.
 //
 //		Err:    err,
@@ -258,13 +429,11 @@ func functionDefinition(signature *types.Function) *Statement {
 		Params(funcDefinitionParams(signature.Results))
 }
 
-// Remove from generating functions that already in existing.
-func removeAlreadyExistingFunctions(existing []types.Function, generating *[]*types.Function, nameFormer func(*types.Function) string) {
-	x := (*generating)[:0]
-	for _, fn := range *generating {
-		if f := util.FindFunctionByName(existing, nameFormer(fn)); f == nil {
-			x = append(x, fn)
-		}
-	}
-	*generating = x
+// Renders marker of function, which is generated for interface.
+// When marked function is not generated anymore, it is commented out in merged file.
+//
+//		//microgen:generated StringService
+//
+func generatedMarker(iface *types.Interface) *Statement {
+	return Comment(write_strategy.GeneratedMarker + " " + iface.Name).Line()
 }
@@ -0,0 +1,46 @@
+package template
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// Prepares template and returns rendered file.
+func renderTemplate(t *testing.T, tmpl Template) string {
+	if err := tmpl.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Render().Render(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// Case of table-driven test of template, which renders go file.
+type renderCase struct {
+	name string
+	tmpl Template
+	// Lines, which show that feature is rendered.
+	contains []string
+}
+
+// Renders templates of cases and checks, that files are valid go code with expected lines.
+func testRender(t *testing.T, cases []renderCase) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			code := renderTemplate(t, c.tmpl)
+			if _, err := parser.ParseFile(token.NewFileSet(), c.tmpl.DefaultPath(), code, parser.AllErrors); err != nil {
+				t.Fatalf("%v\n%s", err, code)
+			}
+			for _, line := range c.contains {
+				if !strings.Contains(code, line) {
+					t.Errorf("%s does not contain %q:\n%s", c.tmpl.DefaultPath(), line, code)
+				}
+			}
+		})
+	}
+}
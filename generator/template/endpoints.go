@@ -13,7 +13,7 @@ type endpointsTemplate struct {
 
 func NewEndpointsTemplate(info *GenerationInfo) Template {
 	return &endpointsTemplate{
-		Info: info,
+		Info: info.qualified(),
 	}
 }
 
@@ -36,6 +36,19 @@ func endpointStructName(str string) string {
 //			CountEndpoint endpoint.Endpoint
 //		}
 //
+//		func NewEndpoints(svc StringService, global []endpoint.Middleware, perMethod map[string][]endpoint.Middleware) *Endpoints {
+//			endpoints := &Endpoints{
+//				CountEndpoint: CountEndpoint(svc),
+//			}
+//			for _, middleware := range perMethod["Count"] {
+//				endpoints.CountEndpoint = middleware(endpoints.CountEndpoint)
+//			}
+//			for _, middleware := range global {
+//				endpoints.CountEndpoint = middleware(endpoints.CountEndpoint)
+//			}
+//			return endpoints
+//		}
+//
 //		func (e *Endpoints) Count(ctx context.Context, text string, symbol string) (count int, positions []int) {
 //			req := CountRequest{
 //				Symbol: symbol,
@@ -60,18 +73,20 @@ func endpointStructName(str string) string {
 //		}
 //
 func (t *endpointsTemplate) Render() write_strategy.Renderer {
-	f := NewFile(t.Info.ServiceImportPackageName)
+	f := t.Info.newFile(EndpointPackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
-	f.Type().Id("Endpoints").StructFunc(func(g *Group) {
+	f.Type().Id(endpointsStructName(t.Info)).StructFunc(func(g *Group) {
 		for _, signature := range t.Info.Iface.Methods {
 			g.Id(endpointStructName(signature.Name)).Qual(PackagePathGoKitEndpoint, "Endpoint")
 		}
 	}).Line()
 
+	f.Add(t.newEndpoints()).Line().Line()
+
 	for _, signature := range t.Info.Iface.Methods {
-		f.Add(serviceEndpointMethod(signature)).Line().Line()
+		f.Add(serviceEndpointMethod(endpointsStructName(t.Info), signature)).Line().Line()
 	}
 	f.Line()
 	for _, signature := range t.Info.Iface.Methods {
@@ -81,8 +96,8 @@ func (t *endpointsTemplate) Render() write_strategy.Renderer {
 	return f
 }
 
-func (endpointsTemplate) DefaultPath() string {
-	return "./endpoints.go"
+func (t *endpointsTemplate) DefaultPath() string {
+	return t.Info.filePath(EndpointsFile)
 }
 
 func (endpointsTemplate) Prepare() error {
@@ -93,6 +108,61 @@ func (t *endpointsTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
 	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
 }
 
+// Returns name of endpoints constructor, e.g. `NewEndpoints`.
+func endpointsConstructorName(info *GenerationInfo) string {
+	return "New" + endpointsStructName(info)
+}
+
+// Render endpoints constructor, which wraps endpoints with middlewares.
+// Middlewares of method are applied first, so global middlewares are called before them.
+//
+//		// NewEndpoints creates endpoints of service and wraps them with middlewares.
+//		// Keys of perMethod are names of service methods.
+//		func NewEndpoints(svc StringService, global []endpoint.Middleware, perMethod map[string][]endpoint.Middleware) *Endpoints {
+//			endpoints := &Endpoints{
+//				CountEndpoint: CountEndpoint(svc),
+//			}
+//			for _, middleware := range perMethod["Count"] {
+//				endpoints.CountEndpoint = middleware(endpoints.CountEndpoint)
+//			}
+//			for _, middleware := range global {
+//				endpoints.CountEndpoint = middleware(endpoints.CountEndpoint)
+//			}
+//			return endpoints
+//		}
+//
+func (t *endpointsTemplate) newEndpoints() *Statement {
+	structName := endpointsStructName(t.Info)
+	constructorName := endpointsConstructorName(t.Info)
+	middlewareType := Qual(PackagePathGoKitEndpoint, "Middleware")
+	wrap := func(fn *types.Function) *Statement {
+		field := Id("endpoints").Dot(endpointStructName(fn.Name))
+		return field.Clone().Op("=").Id("middleware").Call(field.Clone())
+	}
+	return Comment(constructorName+" creates endpoints of service and wraps them with middlewares.").
+		Line().Comment("Keys of perMethod are names of service methods.").
+		Line().Func().Id(constructorName).Params(
+		Id("svc").Qual(t.Info.ServiceImportPath, t.Info.Iface.Name),
+		Id("global").Index().Add(middlewareType.Clone()),
+		Id("perMethod").Map(String()).Index().Add(middlewareType.Clone()),
+	).Params(Op("*").Id(structName)).BlockFunc(func(g *Group) {
+		g.Id("endpoints").Op(":=").Op("&").Id(structName).Values(DictFunc(func(d Dict) {
+			for _, fn := range t.Info.Iface.Methods {
+				d[Id(endpointStructName(fn.Name))] = Id(endpointStructName(fn.Name)).Call(Id("svc"))
+			}
+		}))
+		for _, fn := range t.Info.Iface.Methods {
+			g.For(List(Id("_"), Id("middleware")).Op(":=").Range().Id("perMethod").Index(Lit(fn.Name))).Block(wrap(fn))
+		}
+		g.For(List(Id("_"), Id("middleware")).Op(":=").Range().Id("global")).BlockFunc(func(loop *Group) {
+			for _, fn := range t.Info.Iface.Methods {
+				loop.Add(wrap(fn))
+			}
+		})
+		g.Return(Id("endpoints"))
+	})
+}
+
 // Render full endpoints method.
 //
 //		func (e *Endpoints) Count(ctx context.Context, text string, symbol string) (count int, positions []int) {
@@ -107,8 +177,8 @@ func (t *endpointsTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
 //			return resp.(*CountResponse).Count, resp.(*CountResponse).Positions
 //		}
 //
-func serviceEndpointMethod(signature *types.Function) *Statement {
-	return methodDefinition("Endpoints", signature).
+func serviceEndpointMethod(structName string, signature *types.Function) *Statement {
+	return methodDefinition(structName, signature).
 		BlockFunc(serviceEndpointMethodBody(signature))
 }
 
@@ -130,12 +200,9 @@ func serviceEndpointMethodBody(fn *types.Function) func(g *Group) {
 	return func(g *Group) {
 		g.Id(reqName).Op(":=").Id(requestStructName(fn)).Values(dictByVariables(removeContextIfFirst(fn.Args)))
 		g.Add(endpointResponse(respName, fn)).Id(util.LastUpperOrFirst("Endpoint")).Dot(endpointStructName(fn.Name)).Call(Id(firstArgName(fn)), Op("&").Id(reqName))
-		g.If(Id(nameOfLastResultError(fn)).Op("!=").Nil().Block(
-			If(Qual(PackagePathGoogleGRPC, "Code").Call(Id(nameOfLastResultError(fn))).Op("==").Qual(PackagePathGoogleGRPCCodes, "Internal").Op("||").
-				Qual(PackagePathGoogleGRPC, "Code").Call(Id(nameOfLastResultError(fn))).Op("==").Qual(PackagePathGoogleGRPCCodes, "Unknown").Block(
-				Id(nameOfLastResultError(fn)).Op("=").Qual("errors", "New").Call(Qual(PackagePathGoogleGRPC, "ErrorDesc").Call(Id(nameOfLastResultError(fn)))),
-			)).Line().Return(),
-		))
+		g.If(Id(nameOfLastResultError(fn)).Op("!=").Nil()).Block(
+			Return(),
+		)
 		g.ReturnFunc(func(group *Group) {
 			for _, field := range removeErrorIfLast(fn.Results) {
 				group.Id(respName).Assert(Op("*").Id(responseStructName(fn))).Op(".").Add(structFieldName(&field))
@@ -219,7 +286,7 @@ func createEndpointBody(signature *types.Function) *Statement {
 //
 func createEndpoint(signature *types.Function, info *GenerationInfo) *Statement {
 	return Func().
-		Id(endpointStructName(signature.Name)).Params(Id("svc").Id(info.Iface.Name)).Params(Qual(PackagePathGoKitEndpoint, "Endpoint")).
+		Id(endpointStructName(signature.Name)).Params(Id("svc").Qual(info.ServiceImportPath, info.Iface.Name)).Params(Qual(PackagePathGoKitEndpoint, "Endpoint")).
 		Block(createEndpointBody(signature))
 }
 
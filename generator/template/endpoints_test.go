@@ -0,0 +1,20 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestNewEndpoints(t *testing.T) {
+	text := types.Variable{Base: types.Base{Name: "text"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, "/tmp/out", text)
+
+	testRender(t, []renderCase{
+		{"endpoints", NewEndpointsTemplate(info), []string{
+			"func NewEndpoints(svc StringService, global []endpoint.Middleware, perMethod map[string][]endpoint.Middleware) *Endpoints {",
+			"for _, middleware := range perMethod[\"Count\"] {\n\t\tendpoints.CountEndpoint = middleware(endpoints.CountEndpoint)\n\t}",
+			"for _, middleware := range global {\n\t\tendpoints.CountEndpoint = middleware(endpoints.CountEndpoint)\n\t}",
+		}},
+	})
+}
@@ -0,0 +1,182 @@
+package template
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
+)
+
+const (
+	errorStructName = "Error"
+	errorCodeType   = "Code"
+	errorCoderName  = "Coder"
+	errorCodeOfName = "CodeOf"
+)
+
+// Codes of errors, named after grpc codes.
+// Order is preserved in generated constants.
+var errorCodes = []struct {
+	Name       string
+	Value      string
+	HTTPStatus string
+	GRPCCode   string
+}{
+	{"Unknown", "unknown", "StatusInternalServerError", "Unknown"},
+	{"InvalidArgument", "invalid_argument", "StatusBadRequest", "InvalidArgument"},
+	{"FailedPrecondition", "failed_precondition", "StatusBadRequest", "FailedPrecondition"},
+	{"Unauthenticated", "unauthenticated", "StatusUnauthorized", "Unauthenticated"},
+	{"PermissionDenied", "permission_denied", "StatusForbidden", "PermissionDenied"},
+	{"NotFound", "not_found", "StatusNotFound", "NotFound"},
+	{"AlreadyExists", "already_exists", "StatusConflict", "AlreadyExists"},
+	{"ResourceExhausted", "resource_exhausted", "StatusTooManyRequests", "ResourceExhausted"},
+	{"Internal", "internal", "StatusInternalServerError", "Internal"},
+	{"Unimplemented", "unimplemented", "StatusNotImplemented", "Unimplemented"},
+	{"Unavailable", "unavailable", "StatusServiceUnavailable", "Unavailable"},
+	{"DeadlineExceeded", "deadline_exceeded", "StatusGatewayTimeout", "DeadlineExceeded"},
+}
+
+type errorsTemplate struct {
+	Info *GenerationInfo
+}
+
+func NewErrorsTemplate(info *GenerationInfo) Template {
+	return &errorsTemplate{
+		Info: info,
+	}
+}
+
+// Render errors package, which does not depend on transports.
+// Service returns errors, which implement Coder, and clients receive *Error with the same code.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//		package svcerror
+//
+//		type Code string
+//
+//		const (
+//			Unknown         Code = "unknown"
+//			InvalidArgument Code = "invalid_argument"
+//			...
+//		)
+//
+//		type Coder interface {
+//			error
+//			ErrorCode() Code
+//		}
+//
+//		type Error struct {
+//			Code    Code   `json:"code"`
+//			Message string `json:"message"`
+//		}
+//
+//		func New(code Code, message string) *Error {
+//			return &Error{
+//				Code:    code,
+//				Message: message,
+//			}
+//		}
+//
+//		func (e *Error) Error() string {
+//			return e.Message
+//		}
+//
+//		func (e *Error) ErrorCode() Code {
+//			return e.Code
+//		}
+//
+//		func (e *Error) Is(target error) bool {
+//			t, ok := target.(Coder)
+//			return ok && t.ErrorCode() == e.Code && t.Error() == e.Message
+//		}
+//
+//		func CodeOf(err error) Code {
+//			var c Coder
+//			if errors.As(err, &c) {
+//				return c.ErrorCode()
+//			}
+//			return Unknown
+//		}
+//
+func (t *errorsTemplate) Render() write_strategy.Renderer {
+	f := t.Info.newFile(ErrorPackage)
+	f.PackageComment(FileHeader)
+	f.PackageComment(`Please, do not edit.`)
+
+	f.Comment(errorCodeType + " is transport independent code of error.").
+		Line().Type().Id(errorCodeType).String()
+	f.Line()
+	f.Comment("Codes of errors. Transports map them to http statuses and grpc codes.")
+	f.Const().DefsFunc(func(g *Group) {
+		for _, code := range errorCodes {
+			g.Id(code.Name).Id(errorCodeType).Op("=").Lit(code.Value)
+		}
+	})
+	f.Line()
+
+	f.Comment(errorCoderName+" is implemented by errors, which codes are sent through transports.").
+		Line().Comment("Other errors are sent with "+errorCodes[0].Name+" code.").
+		Line().Type().Id(errorCoderName).Interface(
+		Error(),
+		Id("ErrorCode").Params().Id(errorCodeType),
+	)
+	f.Line()
+
+	f.Comment(errorStructName+" is an error with code, which is restored by clients from responses.").
+		Line().Type().Id(errorStructName).Struct(
+		Id("Code").Id(errorCodeType).Tag(map[string]string{"json": "code"}),
+		Id("Message").String().Tag(map[string]string{"json": "message"}),
+	)
+	f.Line()
+
+	f.Comment("New returns error with code and message.").
+		Line().Func().Id("New").Params(Id("code").Id(errorCodeType), Id("message").String()).Op("*").Id(errorStructName).Block(
+		Return(Op("&").Id(errorStructName).Values(Dict{
+			Id("Code"):    Id("code"),
+			Id("Message"): Id("message"),
+		})),
+	)
+	f.Line()
+
+	f.Func().Params(Id("e").Op("*").Id(errorStructName)).Id("Error").Params().String().Block(
+		Return(Id("e").Dot("Message")),
+	)
+	f.Line()
+
+	f.Func().Params(Id("e").Op("*").Id(errorStructName)).Id("ErrorCode").Params().Id(errorCodeType).Block(
+		Return(Id("e").Dot("Code")),
+	)
+	f.Line()
+
+	f.Comment("Is reports, whether target has the same code and message,").
+		Line().Comment("so errors.Is matches errors, received from clients, with errors of service.").
+		Line().Func().Params(Id("e").Op("*").Id(errorStructName)).Id("Is").Params(Id("target").Error()).Bool().Block(
+		List(Id("t"), Id("ok")).Op(":=").Id("target").Assert(Id(errorCoderName)),
+		Return(Id("ok").Op("&&").Id("t").Dot("ErrorCode").Call().Op("==").Id("e").Dot("Code").
+			Op("&&").Id("t").Dot("Error").Call().Op("==").Id("e").Dot("Message")),
+	)
+	f.Line()
+
+	f.Comment(errorCodeOfName+" returns code of first error in chain, which implements "+errorCoderName+",").
+		Line().Comment("or "+errorCodes[0].Name+", when there is no such error.").
+		Line().Func().Id(errorCodeOfName).Params(Id("err").Error()).Id(errorCodeType).Block(
+		Var().Id("c").Id(errorCoderName),
+		If(Qual(PackagePathErrors, "As").Call(Id("err"), Op("&").Id("c"))).Block(
+			Return(Id("c").Dot("ErrorCode").Call()),
+		),
+		Return(Id(errorCodes[0].Name)),
+	)
+
+	return f
+}
+
+func (t *errorsTemplate) DefaultPath() string {
+	return t.Info.filePath(ErrorsFile)
+}
+
+func (t *errorsTemplate) Prepare() error {
+	return nil
+}
+
+func (t *errorsTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
@@ -0,0 +1,31 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestErrors(t *testing.T) {
+	text := types.Variable{Base: types.Base{Name: "text"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, "/tmp/out", text)
+
+	testRender(t, []renderCase{
+		{"errors", NewErrorsTemplate(info), []string{
+			"NotFound           Code = \"not_found\"",
+			// Codes of wrapped errors are found.
+			"var c Coder\n\tif errors.As(err, &c) {\n\t\treturn c.ErrorCode()\n\t}\n\treturn Unknown",
+		}},
+		{"http errors", NewHttpErrorsTemplate(info), []string{
+			"svcerror.NotFound:           http.StatusNotFound,",
+			"status, ok := httpStatuses[code]\n\tif !ok {\n\t\tstatus = http.StatusInternalServerError\n\t}",
+			"code, ok := httpErrorCodes[r.StatusCode]",
+		}},
+		// Error with unmapped code is sent with unknown code, not with codes.OK.
+		{"grpc errors", NewGRPCErrorsTemplate(info), []string{
+			"svcerror.NotFound:           codes.NotFound,",
+			"code, ok := grpcCodes[svcerror.CodeOf(err)]\n\t\t\tif !ok {\n\t\t\t\tcode = codes.Unknown\n\t\t\t}\n\t\t\treturn nil, status.Error(code, err.Error())",
+			"code, ok := grpcErrorCodes[s.Code()]",
+		}},
+	})
+}
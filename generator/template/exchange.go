@@ -12,7 +12,7 @@ type exchangeTemplate struct {
 
 func NewExchangeTemplate(info *GenerationInfo) Template {
 	return &exchangeTemplate{
-		Info: info,
+		Info: info.qualified(),
 	}
 }
 
@@ -42,20 +42,20 @@ func responseStructName(signature *types.Function) string {
 //  }
 //
 func (t *exchangeTemplate) Render() write_strategy.Renderer {
-	f := NewFile(t.Info.ServiceImportPackageName)
+	f := t.Info.newFile(EndpointPackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
 	for _, signature := range t.Info.Iface.Methods {
-		f.Add(exchange(requestStructName(signature), removeContextIfFirst(signature.Args))).Line()
-		f.Add(exchange(responseStructName(signature), removeErrorIfLast(signature.Results))).Line()
+		f.Add(exchange(requestStructName(signature), removeContextIfFirst(signature.Args), t.Info.JSONNaming)).Line()
+		f.Add(exchange(responseStructName(signature), removeErrorIfLast(signature.Results), t.Info.JSONNaming)).Line()
 	}
 
 	return f
 }
 
-func (exchangeTemplate) DefaultPath() string {
-	return "./exchanges.go"
+func (t *exchangeTemplate) DefaultPath() string {
+	return t.Info.filePath(ExchangesFile)
 }
 
 func (exchangeTemplate) Prepare() error {
@@ -72,7 +72,7 @@ func (t *exchangeTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
 //  	Visit *entity.Visit `json:"visit"`
 //  }
 //
-func exchange(name string, params []types.Variable) Code {
+func exchange(name string, params []types.Variable, naming string) Code {
 	if len(params) == 0 {
 		return Comment("Formal exchange type, please do not delete").Line().
 			Type().Id(name).Struct().
@@ -80,7 +80,7 @@ func exchange(name string, params []types.Variable) Code {
 	}
 	return Type().Id(name).StructFunc(func(g *Group) {
 		for _, param := range params {
-			g.Add(structField(&param))
+			g.Add(structField(&param, naming))
 		}
 	}).Line()
 }
@@ -24,10 +24,6 @@ func NewGRPCClientTemplate(info *GenerationInfo) Template {
 	}
 }
 
-func (t *gRPCClientTemplate) grpcConverterPackagePath() string {
-	return t.Info.ServiceImportPath + "/transport/converter/protobuf"
-}
-
 // Render whole grpc client file.
 //
 //		// This file was automatically generated by "microgen" utility.
@@ -43,7 +39,7 @@ func (t *gRPCClientTemplate) grpcConverterPackagePath() string {
 //		)
 //
 //		func NewGRPCClient(conn *grpc.ClientConn, opts ...grpc1.ClientOption) svc.StringService {
-//			return &svc.Endpoints{CountEndpoint: grpc1.NewClient(
+//			return &svc.Endpoints{CountEndpoint: decodeGRPCErrors(grpc1.NewClient(
 //				conn,
 //				"devim.string.protobuf.StringService",
 //				"Count",
@@ -51,31 +47,34 @@ func (t *gRPCClientTemplate) grpcConverterPackagePath() string {
 //				protobuf.DecodeCountResponse,
 //				stringsvc.CountResponse{},
 //				opts...,
-//			).Endpoint()}
+//			).Endpoint())}
 //		}
 //
 func (t *gRPCClientTemplate) Render() write_strategy.Renderer {
-	f := NewFile("transportgrpc")
+	f := t.Info.newFile(GRPCTransportPackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
 	f.Func().Id("NewGRPCClient").
 		Params(
 			Id("conn").Op("*").Qual(PackagePathGoogleGRPC, "ClientConn"),
+			tracingParams(t.Info),
 			Id("opts").Op("...").Qual(PackagePathGoKitTransportGRPC, "ClientOption"),
 		).Qual(t.Info.ServiceImportPath, t.Info.Iface.Name).
 		BlockFunc(func(g *Group) {
-			g.Return().Op("&").Qual(t.Info.ServiceImportPath, "Endpoints").Values(DictFunc(func(d Dict) {
+			g.Return().Op("&").Qual(t.Info.importPath(EndpointPackage), endpointsStructName(t.Info)).Values(DictFunc(func(d Dict) {
 				for _, m := range t.Info.Iface.Methods {
-					d[Id(endpointStructName(m.Name))] = Qual(PackagePathGoKitTransportGRPC, "NewClient").Call(
+					d[Id(endpointStructName(m.Name))] = Id(grpcErrorDecoderName).Call(Qual(PackagePathGoKitTransportGRPC, "NewClient").Call(
 						Line().Id("conn"),
 						Line().Lit(t.Info.GRPCRegAddr),
 						Line().Lit(m.Name),
-						Line().Qual(pathToConverter(t.Info.ServiceImportPath), requestEncodeName(m)),
-						Line().Qual(pathToConverter(t.Info.ServiceImportPath), responseDecodeName(m)),
+						Line().Qual(pathToConverter(t.Info), requestEncodeName(m)),
+						Line().Qual(pathToConverter(t.Info), responseDecodeName(m)),
 						Line().Add(t.replyType(m)),
-						Line().Id("opts").Op("...").Line(),
-					).Dot("Endpoint").Call()
+						Line().Add(tracingOpts(t.Info, Qual(PackagePathGoKitTransportGRPC, "ClientBefore").Call(
+							Qual(PackagePathGoKitOpenTracing, "ContextToGRPC").Call(Id(tracerVarName), Id(loggerVarName)),
+						))).Line(),
+					).Dot("Endpoint").Call())
 				}
 			}))
 		})
@@ -91,8 +90,8 @@ func (t *gRPCClientTemplate) replyType(signature *types.Function) *Statement {
 	return Qual(t.Info.ProtobufPackage, responseStructName(signature)).Values()
 }
 
-func (gRPCClientTemplate) DefaultPath() string {
-	return "./transport/grpc/client.go"
+func (t *gRPCClientTemplate) DefaultPath() string {
+	return t.Info.filePath(GRPCClientFile)
 }
 
 func (t *gRPCClientTemplate) Prepare() error {
@@ -103,7 +102,7 @@ func (t *gRPCClientTemplate) Prepare() error {
 		return ProtobufEmptyError
 	}
 
-	tags := util.FetchTags(t.Info.Iface.Docs, TagMark+ForceTag)
+	tags := t.Info.ForceTags
 	if util.IsInStringSlice("grpc", tags) || util.IsInStringSlice("grpc-client", tags) {
 		t.Info.Force = true
 	}
@@ -2,7 +2,6 @@ package template
 
 import (
 	"fmt"
-	"path/filepath"
 
 	. "github.com/dave/jennifer/jen"
 	"github.com/devimteam/microgen/generator/write_strategy"
@@ -11,21 +10,43 @@ import (
 )
 
 var (
-	defaultProtoTypes = []string{"string", "bool", "byte", "int64", "uint64", "float64", "int32", "uint32", "float32"}
-	goToProtoTypesMap = map[string]string{
-		"uint": "uint64",
-		"int":  "int64",
+	// Protobuf scalars by golang types, which are converted to them.
+	// Used by proto definition, endpoint and type converters.
+	//
+	//		int8 -> int32 in proto file and int32 in generated golang code
+	//
+	goToProtoScalars = map[string]protoScalar{
+		"string":  {proto: "string", golang: "string"},
+		"bool":    {proto: "bool", golang: "bool"},
+		"int":     {proto: "int64", golang: "int64"},
+		"int8":    {proto: "int32", golang: "int32"},
+		"int16":   {proto: "int32", golang: "int32"},
+		"int32":   {proto: "int32", golang: "int32"},
+		"int64":   {proto: "int64", golang: "int64"},
+		"uint":    {proto: "uint64", golang: "uint64"},
+		"uint8":   {proto: "uint32", golang: "uint32"},
+		"uint16":  {proto: "uint32", golang: "uint32"},
+		"uint32":  {proto: "uint32", golang: "uint32"},
+		"uint64":  {proto: "uint64", golang: "uint64"},
+		"byte":    {proto: "uint32", golang: "uint32"},
+		"rune":    {proto: "int32", golang: "int32"},
+		"float32": {proto: "float", golang: "float32"},
+		"float64": {proto: "double", golang: "float64"},
 	}
-	defaultGolangTypes = []string{"string", "bool", "int", "uint", "byte", "int64", "uint64", "float64", "int32", "uint32", "float32"}
 )
 
+// Protobuf scalar: name of type in proto file and golang type of generated field.
+type protoScalar struct {
+	proto  string
+	golang string
+}
+
 type gRPCEndpointConverterTemplate struct {
 	Info             *GenerationInfo
 	requestEncoders  []*types.Function
 	requestDecoders  []*types.Function
 	responseEncoders []*types.Function
 	responseDecoders []*types.Function
-	state            WriteStrategyState
 }
 
 func NewGRPCEndpointConverterTemplate(info *GenerationInfo) Template {
@@ -51,6 +72,7 @@ func responseEncodeName(f *types.Function) string {
 }
 
 // Renders converter file.
+// Existing file is merged, converters of removed methods are commented out.
 //
 //		// This file was automatically generated by "microgen" utility.
 //		// Please, do not edit.
@@ -62,6 +84,7 @@ func responseEncodeName(f *types.Function) string {
 //			stringsvc "gitlab.devim.team/protobuf/stringsvc"
 //		)
 //
+//		//microgen:generated StringService
 //		func EncodeCountRequest(_ context.Context, request interface{}) (interface{}, error) {
 //			req := request.(*svc.CountRequest)
 //			return &stringsvc.CountRequest{
@@ -106,23 +129,19 @@ func (t *gRPCEndpointConverterTemplate) Render() write_strategy.Renderer {
 	f := &Statement{}
 
 	for _, signature := range t.requestEncoders {
-		f.Line().Add(t.encodeRequest(signature))
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.encodeRequest(signature))
 	}
 	for _, signature := range t.responseEncoders {
-		f.Line().Add(t.encodeResponse(signature))
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.encodeResponse(signature))
 	}
 	for _, signature := range t.requestDecoders {
-		f.Line().Add(t.decodeRequest(signature))
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.decodeRequest(signature))
 	}
 	for _, signature := range t.responseDecoders {
-		f.Line().Add(t.decodeResponse(signature))
-	}
-
-	if t.state == AppendStrat {
-		return f
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.decodeResponse(signature))
 	}
 
-	file := NewFile("protobuf")
+	file := t.Info.newFile(GRPCConverterPackage)
 	file.PackageComment(FileHeader)
 	file.PackageComment(`Please, do not change functions names!`)
 	file.Add(f)
@@ -161,6 +180,9 @@ Loop:
 				methodName += _list
 			}
 			field = f.Next
+		case types.TEllipsis:
+			methodName += _slice
+			field = f.Next
 		case types.TMap:
 			methodName += _map + typeToProto(f.Key, 1) + typeToProto(f.Value, 1)
 			field = nil
@@ -208,6 +230,9 @@ Loop:
 				methodName += _list
 			}
 			field = f.Next
+		case types.TEllipsis:
+			methodName += _slice
+			field = f.Next
 		case types.TMap:
 			methodName += _map + typeToProto(f.Key, 1) + typeToProto(f.Value, 1)
 			field = nil
@@ -228,8 +253,8 @@ Loop:
 	return methodName
 }
 
-func (gRPCEndpointConverterTemplate) DefaultPath() string {
-	return "./transport/converter/protobuf/endpoint_converters.go"
+func (t *gRPCEndpointConverterTemplate) DefaultPath() string {
+	return t.Info.filePath(GRPCEndpointConvertersFile)
 }
 
 func (t *gRPCEndpointConverterTemplate) Prepare() error {
@@ -246,22 +271,10 @@ func (t *gRPCEndpointConverterTemplate) Prepare() error {
 }
 
 func (t *gRPCEndpointConverterTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
-	if err := util.StatFile(t.Info.AbsOutPath, t.DefaultPath()); t.Info.Force || err != nil {
-		t.state = FileStrat
+	if t.Info.Force {
 		return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
 	}
-	file, err := util.ParseFile(filepath.Join(t.Info.AbsOutPath, t.DefaultPath()))
-	if err != nil {
-		return nil, err
-	}
-
-	removeAlreadyExistingFunctions(file.Functions, &t.requestEncoders, requestEncodeName)
-	removeAlreadyExistingFunctions(file.Functions, &t.requestDecoders, requestDecodeName)
-	removeAlreadyExistingFunctions(file.Functions, &t.responseEncoders, responseEncodeName)
-	removeAlreadyExistingFunctions(file.Functions, &t.responseDecoders, responseDecodeName)
-
-	t.state = AppendStrat
-	return write_strategy.NewAppendToFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+	return write_strategy.NewMergeFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
 }
 
 func isPointer(p types.Type) bool {
@@ -287,18 +300,8 @@ func golangTypeToProto(structName string, field *types.Variable) (*Statement, bo
 	if name == nil {
 		return Id(structName + util.ToUpperFirst(field.Name)), false
 	}
-	if newType, ok := goToProtoTypesMap[*name]; ok {
-		var newField types.Type
-		newField = types.TName{
-			TypeName: newType,
-		}
-		if imp := types.TypeImport(field.Type); imp != nil {
-			newField = types.TImport{
-				Next:   newField,
-				Import: imp,
-			}
-		}
-		return fieldType(newField, false).Call(Id(structName).Dot(util.ToUpperFirst(field.Name))), true
+	if scalar, ok := goToProtoScalars[*name]; ok {
+		return Id(scalar.golang).Call(Id(structName).Dot(util.ToUpperFirst(field.Name))), true
 	}
 	return Id(structName + util.ToUpperFirst(field.Name)), false
 }
@@ -320,12 +323,20 @@ func protoTypeToGolang(structName string, field *types.Variable) (*Statement, bo
 
 func isDefaultProtoField(field *types.Variable) bool {
 	name := types.TypeName(field.Type)
-	return name != nil && util.IsInStringSlice(*name, defaultProtoTypes)
+	if name == nil {
+		return false
+	}
+	scalar, ok := goToProtoScalars[*name]
+	return ok && scalar.golang == *name
 }
 
 func isDefaultGolangField(field *types.Variable) bool {
 	name := types.TypeName(field.Type)
-	return name != nil && util.IsInStringSlice(*name, defaultGolangTypes)
+	if name == nil {
+		return false
+	}
+	_, ok := goToProtoScalars[*name]
+	return ok
 }
 
 // Render custom type converting and error checking
@@ -363,7 +374,7 @@ func (t *gRPCEndpointConverterTemplate) encodeRequest(signature *types.Function)
 	return Line().Func().Id(requestEncodeName(signature)).Params(Op("_").Qual(PackagePathContext, "Context"), Id("request").Interface()).Params(Interface(), Error()).BlockFunc(
 		func(group *Group) {
 			if len(methodParams) > 0 {
-				group.Id("req").Op(":=").Id("request").Assert(Op("*").Qual(t.Info.ServiceImportPath, requestStructName(signature)))
+				group.Id("req").Op(":=").Id("request").Assert(Op("*").Qual(t.Info.importPath(EndpointPackage), requestStructName(signature)))
 				for _, field := range methodParams {
 					if _, ok := golangTypeToProto("", &field); !ok {
 						group.Add(t.convertCustomType("req", typeToProto(field.Type, 0), &field))
@@ -413,7 +424,7 @@ func (t *gRPCEndpointConverterTemplate) encodeResponse(signature *types.Function
 	return Line().Func().Id(responseEncodeName(signature)).Call(Op("_").Qual(PackagePathContext, "Context"), Id("response").Interface()).Params(Interface(), Error()).BlockFunc(
 		func(group *Group) {
 			if len(methodResults) > 0 {
-				group.Id("resp").Op(":=").Id("response").Assert(Op("*").Qual(t.Info.ServiceImportPath, responseStructName(signature)))
+				group.Id("resp").Op(":=").Id("response").Assert(Op("*").Qual(t.Info.importPath(EndpointPackage), responseStructName(signature)))
 				for _, field := range methodResults {
 					if _, ok := golangTypeToProto("", &field); !ok {
 						group.Add(t.convertCustomType("resp", typeToProto(field.Type, 0), &field))
@@ -447,7 +458,7 @@ func (t *gRPCEndpointConverterTemplate) decodeRequest(signature *types.Function)
 					}
 				}
 			}
-			group.Return().List(t.grpcEndpointConvReturn(signature, methodParams, requestStructName, "req", protoTypeToGolang, t.Info.ServiceImportPath), Nil())
+			group.Return().List(t.grpcEndpointConvReturn(signature, methodParams, requestStructName, "req", protoTypeToGolang, t.Info.importPath(EndpointPackage)), Nil())
 		},
 	).Line()
 }
@@ -478,7 +489,7 @@ func (t *gRPCEndpointConverterTemplate) decodeResponse(signature *types.Function
 					}
 				}
 			}
-			group.Return().List(t.grpcEndpointConvReturn(signature, methodResults, responseStructName, "resp", protoTypeToGolang, t.Info.ServiceImportPath), Nil())
+			group.Return().List(t.grpcEndpointConvReturn(signature, methodResults, responseStructName, "resp", protoTypeToGolang, t.Info.importPath(EndpointPackage)), Nil())
 		},
 	).Line()
 }
@@ -0,0 +1,157 @@
+package template
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
+)
+
+const (
+	grpcErrorEncoderName = "encodeGRPCErrors"
+	grpcErrorDecoderName = "decodeGRPCErrors"
+)
+
+type gRPCErrorsTemplate struct {
+	Info *GenerationInfo
+}
+
+func NewGRPCErrorsTemplate(info *GenerationInfo) Template {
+	return &gRPCErrorsTemplate{
+		Info: info,
+	}
+}
+
+// Render endpoint middlewares, which convert errors with codes to grpc statuses and back.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//		package transportgrpc
+//
+//		var grpcCodes = map[svcerror.Code]codes.Code{
+//			svcerror.Unknown:         codes.Unknown,
+//			svcerror.InvalidArgument: codes.InvalidArgument,
+//			...
+//		}
+//
+//		var grpcErrorCodes = map[codes.Code]svcerror.Code{
+//			codes.Unknown:         svcerror.Unknown,
+//			codes.InvalidArgument: svcerror.InvalidArgument,
+//			...
+//		}
+//
+//		func encodeGRPCErrors(e endpoint.Endpoint) endpoint.Endpoint {
+//			return func(ctx context.Context, request interface{}) (interface{}, error) {
+//				response, err := e(ctx, request)
+//				if err != nil {
+//					code, ok := grpcCodes[svcerror.CodeOf(err)]
+//					if !ok {
+//						code = codes.Unknown
+//					}
+//					return nil, status.Error(code, err.Error())
+//				}
+//				return response, nil
+//			}
+//		}
+//
+//		func decodeGRPCErrors(e endpoint.Endpoint) endpoint.Endpoint {
+//			return func(ctx context.Context, request interface{}) (interface{}, error) {
+//				response, err := e(ctx, request)
+//				if err != nil {
+//					if s, ok := status.FromError(err); ok {
+//						code, ok := grpcErrorCodes[s.Code()]
+//						if !ok {
+//							code = svcerror.Unknown
+//						}
+//						return nil, svcerror.New(code, s.Message())
+//					}
+//					return nil, err
+//				}
+//				return response, nil
+//			}
+//		}
+//
+func (t *gRPCErrorsTemplate) Render() write_strategy.Renderer {
+	errPkg := t.Info.importPath(ErrorPackage)
+	f := t.Info.newFile(GRPCTransportPackage)
+	f.PackageComment(FileHeader)
+	f.PackageComment(`Please, do not edit.`)
+
+	f.Comment("Grpc codes of error codes.").
+		Line().Var().Id("grpcCodes").Op("=").Map(Qual(errPkg, errorCodeType)).Qual(PackagePathGoogleGRPCCodes, "Code").Values(DictFunc(func(d Dict) {
+		for _, code := range errorCodes {
+			d[Qual(errPkg, code.Name)] = Qual(PackagePathGoogleGRPCCodes, code.GRPCCode)
+		}
+	}))
+	f.Line()
+	f.Comment("Error codes of grpc codes.").
+		Line().Var().Id("grpcErrorCodes").Op("=").Map(Qual(PackagePathGoogleGRPCCodes, "Code")).Qual(errPkg, errorCodeType).Values(DictFunc(func(d Dict) {
+		for _, code := range errorCodes {
+			d[Qual(PackagePathGoogleGRPCCodes, code.GRPCCode)] = Qual(errPkg, code.Name)
+		}
+	}))
+	f.Line()
+
+	f.Comment(grpcErrorEncoderName + " converts errors of endpoint to grpc statuses with codes of errors.").
+		Line().Add(t.errorsMiddleware(grpcErrorEncoderName, func(g *Group) {
+		// Unknown code is sent, when code of error is not mapped, because codes.OK drops error.
+		g.List(Id("code"), Id("ok")).Op(":=").Id("grpcCodes").Index(Qual(errPkg, errorCodeOfName).Call(Err()))
+		g.If(Op("!").Id("ok")).Block(
+			Id("code").Op("=").Qual(PackagePathGoogleGRPCCodes, "Unknown"),
+		)
+		g.Return(Nil(), Qual(PackagePathGoogleGRPCStatus, "Error").Call(Id("code"), Err().Dot("Error").Call()))
+	}))
+	f.Line()
+
+	f.Comment(grpcErrorDecoderName + " converts grpc statuses, returned by endpoint, to errors with codes.").
+		Line().Add(t.errorsMiddleware(grpcErrorDecoderName, func(g *Group) {
+		g.If(List(Id("s"), Id("ok")).Op(":=").Qual(PackagePathGoogleGRPCStatus, "FromError").Call(Err()), Id("ok")).Block(
+			List(Id("code"), Id("ok")).Op(":=").Id("grpcErrorCodes").Index(Id("s").Dot("Code").Call()),
+			If(Op("!").Id("ok")).Block(
+				Id("code").Op("=").Qual(errPkg, errorCodes[0].Name),
+			),
+			Return(Nil(), Qual(errPkg, "New").Call(Id("code"), Id("s").Dot("Message").Call())),
+		)
+		g.Return(Nil(), Err())
+	}))
+
+	return f
+}
+
+// Renders endpoint middleware, which handles errors of endpoint.
+//
+//		func encodeGRPCErrors(e endpoint.Endpoint) endpoint.Endpoint {
+//			return func(ctx context.Context, request interface{}) (interface{}, error) {
+//				response, err := e(ctx, request)
+//				if err != nil {
+//					...
+//				}
+//				return response, nil
+//			}
+//		}
+//
+func (t *gRPCErrorsTemplate) errorsMiddleware(name string, handle func(g *Group)) *Statement {
+	return Func().Id(name).Params(Id("e").Qual(PackagePathGoKitEndpoint, "Endpoint")).Qual(PackagePathGoKitEndpoint, "Endpoint").Block(
+		Return(Func().Params(
+			Id("ctx").Qual(PackagePathContext, "Context"),
+			Id("request").Interface(),
+		).Params(
+			Interface(),
+			Error(),
+		).Block(
+			List(Id("response"), Err()).Op(":=").Id("e").Call(Id("ctx"), Id("request")),
+			If(Err().Op("!=").Nil()).BlockFunc(handle),
+			Return(Id("response"), Nil()),
+		)),
+	)
+}
+
+func (t *gRPCErrorsTemplate) DefaultPath() string {
+	return t.Info.filePath(GRPCErrorsFile)
+}
+
+func (t *gRPCErrorsTemplate) Prepare() error {
+	return nil
+}
+
+func (t *gRPCErrorsTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
@@ -1,8 +1,6 @@
 package template
 
 import (
-	"path/filepath"
-
 	. "github.com/dave/jennifer/jen"
 	"github.com/devimteam/microgen/generator/write_strategy"
 	"github.com/devimteam/microgen/util"
@@ -27,8 +25,8 @@ func privateServerStructName(iface *types.Interface) string {
 	return util.ToLower(iface.Name) + "Server"
 }
 
-func pathToConverter(servicePath string) string {
-	return filepath.Join(servicePath, "transport/converter/protobuf")
+func pathToConverter(info *GenerationInfo) string {
+	return info.importPath(GRPCConverterPackage)
 }
 
 // Render whole grpc server file.
@@ -51,7 +49,7 @@ func pathToConverter(servicePath string) string {
 //
 //		func NewGRPCServer(endpoints *svc.Endpoints, opts ...grpc.ServerOption) stringsvc.StringServiceServer {
 //			return &stringServiceServer{count: grpc.NewServer(
-//				endpoints.CountEndpoint,
+//				encodeGRPCErrors(endpoints.CountEndpoint),
 //				protobuf.DecodeCountRequest,
 //				protobuf.EncodeCountResponse,
 //				opts...,
@@ -67,7 +65,7 @@ func pathToConverter(servicePath string) string {
 //		}
 //
 func (t *gRPCServerTemplate) Render() write_strategy.Renderer {
-	f := NewFile("transportgrpc")
+	f := t.Info.newFile(GRPCTransportPackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
@@ -79,7 +77,8 @@ func (t *gRPCServerTemplate) Render() write_strategy.Renderer {
 
 	f.Func().Id("NewGRPCServer").
 		Params(
-			Id("endpoints").Op("*").Qual(t.Info.ServiceImportPath, "Endpoints"),
+			Id("endpoints").Op("*").Qual(t.Info.importPath(EndpointPackage), endpointsStructName(t.Info)),
+			tracingParams(t.Info),
 			Id("opts").Op("...").Qual(PackagePathGoKitTransportGRPC, "ServerOption"),
 		).Params(
 		Qual(t.Info.ProtobufPackage, serverStructName(t.Info.Iface)),
@@ -89,10 +88,12 @@ func (t *gRPCServerTemplate) Render() write_strategy.Renderer {
 				for _, m := range t.Info.Iface.Methods {
 					g[(&Statement{}).Id(util.ToLowerFirst(m.Name))] = Qual(PackagePathGoKitTransportGRPC, "NewServer").
 						Call(
-							Line().Id("endpoints").Dot(endpointStructName(m.Name)),
-							Line().Qual(pathToConverter(t.Info.ServiceImportPath), requestDecodeName(m)),
-							Line().Qual(pathToConverter(t.Info.ServiceImportPath), responseEncodeName(m)),
-							Line().Id("opts").Op("...").Line(),
+							Line().Id(grpcErrorEncoderName).Call(Id("endpoints").Dot(endpointStructName(m.Name))),
+							Line().Qual(pathToConverter(t.Info), requestDecodeName(m)),
+							Line().Qual(pathToConverter(t.Info), responseEncodeName(m)),
+							Line().Add(tracingOpts(t.Info, Qual(PackagePathGoKitTransportGRPC, "ServerBefore").Call(
+								Qual(PackagePathGoKitOpenTracing, "GRPCToContext").Call(Id(tracerVarName), Lit(m.Name), Id(loggerVarName)),
+							))).Line(),
 						)
 				}
 			}),
@@ -108,8 +109,8 @@ func (t *gRPCServerTemplate) Render() write_strategy.Renderer {
 	return f
 }
 
-func (gRPCServerTemplate) DefaultPath() string {
-	return "./transport/grpc/server.go"
+func (t *gRPCServerTemplate) DefaultPath() string {
+	return t.Info.filePath(GRPCServerFile)
 }
 
 func (t *gRPCServerTemplate) Prepare() error {
@@ -117,7 +118,7 @@ func (t *gRPCServerTemplate) Prepare() error {
 		return ProtobufEmptyError
 	}
 
-	tags := util.FetchTags(t.Info.Iface.Docs, TagMark+ForceTag)
+	tags := t.Info.ForceTags
 	if util.IsInStringSlice("grpc", tags) || util.IsInStringSlice("grpc-server", tags) {
 		t.Info.Force = true
 	}
@@ -7,7 +7,8 @@ import (
 )
 
 type httpClientTemplate struct {
-	Info *GenerationInfo
+	Info   *GenerationInfo
+	routes map[string]*httpRoute
 }
 
 func NewHttpClientTemplate(info *GenerationInfo) Template {
@@ -17,7 +18,7 @@ func NewHttpClientTemplate(info *GenerationInfo) Template {
 }
 
 func (t *httpClientTemplate) DefaultPath() string {
-	return "./transport/http/client.go"
+	return t.Info.filePath(HTTPClientFile)
 }
 
 func (t *httpClientTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
@@ -28,11 +29,13 @@ func (t *httpClientTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
 }
 
 func (t *httpClientTemplate) Prepare() error {
-	tags := util.FetchTags(t.Info.Iface.Docs, TagMark+ForceTag)
+	tags := t.Info.ForceTags
 	if util.IsInStringSlice("http", tags) || util.IsInStringSlice("http-client", tags) {
 		t.Info.Force = true
 	}
-	return nil
+	var err error
+	t.routes, err = newHTTPRoutes(t.Info.Iface)
+	return err
 }
 
 // Render http client.
@@ -82,12 +85,13 @@ func (t *httpClientTemplate) Prepare() error {
 //		}
 //
 func (t *httpClientTemplate) Render() write_strategy.Renderer {
-	f := NewFile("transporthttp")
+	f := t.Info.newFile(HTTPTransportPackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
 	f.Func().Id("NewHTTPClient").Params(
 		Id("addr").Id("string"),
+		tracingParams(t.Info),
 		Id("opts").Op("...").Qual(PackagePathGoKitTransportHTTP, "ClientOption"),
 	).Params(
 		Qual(t.Info.ServiceImportPath, t.Info.Iface.Name),
@@ -142,15 +146,17 @@ func (t *httpClientTemplate) clientBody() *Statement {
 		Block(
 			Return(Nil(), Err()),
 		).
-		Line().Return(Op("&").Qual(t.Info.ServiceImportPath, "Endpoints").Values(DictFunc(
+		Line().Return(Op("&").Qual(t.Info.importPath(EndpointPackage), endpointsStructName(t.Info)).Values(DictFunc(
 		func(d Dict) {
 			for _, fn := range t.Info.Iface.Methods {
 				d[Id(endpointStructName(fn.Name))] = Qual(PackagePathGoKitTransportHTTP, "NewClient").Call(
-					Line().Lit("POST"), // TODO: customize POST
+					Line().Lit(t.routes[fn.Name].Method),
 					Line().Id("u"),
-					Line().Qual(pathToHttpConverter(t.Info.ServiceImportPath), httpEncodeRequestName(fn)),
-					Line().Qual(pathToHttpConverter(t.Info.ServiceImportPath), httpDecodeResponseName(fn)),
-					Line().Id("opts").Op("...").Line(),
+					Line().Qual(pathToHttpConverter(t.Info), httpEncodeRequestName(fn)),
+					Line().Qual(pathToHttpConverter(t.Info), httpDecodeResponseName(fn)),
+					Line().Add(tracingOpts(t.Info, Qual(PackagePathGoKitTransportHTTP, "ClientBefore").Call(
+						Qual(PackagePathGoKitOpenTracing, "ContextToHTTP").Call(Id(tracerVarName), Id(loggerVarName)),
+					))).Line(),
 				).Dot("Endpoint").Call()
 			}
 		},
@@ -1,7 +1,7 @@
 package template
 
 import (
-	"path/filepath"
+	"strings"
 
 	. "github.com/dave/jennifer/jen"
 	"github.com/devimteam/microgen/generator/write_strategy"
@@ -15,60 +15,37 @@ const (
 )
 
 type httpConverterTemplate struct {
-	Info                         *GenerationInfo
-	encodersRequest              []*types.Function
-	decodersRequest              []*types.Function
-	encodersResponse             []*types.Function
-	decodersResponse             []*types.Function
-	state                        WriteStrategyState
-	isCommonEncoderRequestExist  bool
-	isCommonEncoderResponseExist bool
+	Info             *GenerationInfo
+	encodersRequest  []*types.Function
+	decodersRequest  []*types.Function
+	encodersResponse []*types.Function
+	decodersResponse []*types.Function
+	routes           map[string]*httpRoute
 }
 
 func NewHttpConverterTemplate(info *GenerationInfo) Template {
 	return &httpConverterTemplate{
-		Info: info,
+		Info: info.qualified(),
 	}
 }
 
 func (t *httpConverterTemplate) DefaultPath() string {
-	return "./transport/converter/http/exchange_converters.go"
+	return t.Info.filePath(HTTPConvertersFile)
 }
 
 func (t *httpConverterTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
-	if err := util.StatFile(t.Info.AbsOutPath, t.DefaultPath()); t.Info.Force || err != nil {
-		t.state = FileStrat
+	if t.Info.Force {
 		return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
 	}
-	file, err := util.ParseFile(filepath.Join(t.Info.AbsOutPath, t.DefaultPath()))
-	if err != nil {
-		return nil, err
-	}
-
-	removeAlreadyExistingFunctions(file.Functions, &t.encodersRequest, httpEncodeRequestName)
-	removeAlreadyExistingFunctions(file.Functions, &t.decodersRequest, httpDecodeRequestName)
-	removeAlreadyExistingFunctions(file.Functions, &t.encodersResponse, httpEncodeResponseName)
-	removeAlreadyExistingFunctions(file.Functions, &t.decodersResponse, httpDecodeResponseName)
-
-	for i := range file.Functions {
-		if file.Functions[i].Name == commonResponseEncoderName {
-			t.isCommonEncoderResponseExist = true
-			continue
-		}
-		if file.Functions[i].Name == commonRequestEncoderName {
-			t.isCommonEncoderRequestExist = true
-			continue
-		}
-		if t.isCommonEncoderRequestExist && t.isCommonEncoderResponseExist {
-			break
-		}
-	}
-
-	t.state = AppendStrat
-	return write_strategy.NewAppendToFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+	return write_strategy.NewMergeFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
 }
 
 func (t *httpConverterTemplate) Prepare() error {
+	var err error
+	t.routes, err = newHTTPRoutes(t.Info.Iface)
+	if err != nil {
+		return err
+	}
 	for _, fn := range t.Info.Iface.Methods {
 		t.decodersRequest = append(t.decodersRequest, fn)
 		t.encodersRequest = append(t.encodersRequest, fn)
@@ -79,6 +56,8 @@ func (t *httpConverterTemplate) Prepare() error {
 }
 
 // Render http converters: for exchanges and common.
+// Existing file is merged: converters, which were not changed, are refreshed,
+// converters of removed methods are commented out.
 //		// This file was automatically generated by "microgen" utility.
 //		// Please, do not change functions names!
 //		package httpconv
@@ -106,19 +85,26 @@ func (t *httpConverterTemplate) Prepare() error {
 //			return json.NewEncoder(w).Encode(response)
 //		}
 //
+//		//microgen:generated StringService
 //		func DecodeHTTPCountRequest(_ context.Context, r *http.Request) (interface{}, error) {
 //			var req svc.CountRequest
-//			err := json.NewDecoder(r.Body).Decode(&req)
-//			return req, err
+//			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+//				return nil, err
+//			}
+//			return &req, nil
 //		}
 //
 //		func DecodeHTTPCountResponse(_ context.Context, r *http.Response) (interface{}, error) {
+//			if r.StatusCode >= http.StatusBadRequest {
+//				return nil, ErrorDecoder(r)
+//			}
 //			var resp svc.CountResponse
 //			err := json.NewDecoder(r.Body).Decode(&resp)
-//			return resp, err
+//			return &resp, err
 //		}
 //
 //		func EncodeHTTPCountRequest(ctx context.Context, r *http.Request, request interface{}) error {
+//			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/") + "/count"
 //			return DefaultRequestEncoder(ctx, r, request)
 //		}
 //
@@ -129,31 +115,23 @@ func (t *httpConverterTemplate) Prepare() error {
 func (t *httpConverterTemplate) Render() write_strategy.Renderer {
 	f := &Statement{}
 
-	if !t.isCommonEncoderRequestExist {
-		f.Line().Add(commonEncoderRequest()).Line()
-	}
-	if !t.isCommonEncoderResponseExist {
-		f.Line().Add(commonEncoderResponse()).Line()
-	}
+	f.Line().Add(generatedMarker(t.Info.Iface)).Add(commonEncoderRequest()).Line()
+	f.Line().Add(generatedMarker(t.Info.Iface)).Add(commonEncoderResponse()).Line()
 
 	for _, fn := range t.decodersRequest {
-		f.Line().Add(t.decodeHttpRequest(fn)).Line()
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.decodeHttpRequest(fn)).Line()
 	}
 	for _, fn := range t.decodersResponse {
-		f.Line().Add(t.decodeHttpResponse(fn)).Line()
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.decodeHttpResponse(fn)).Line()
 	}
 	for _, fn := range t.encodersRequest {
-		f.Line().Add(encodeHttpRequest(fn)).Line()
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.encodeHttpRequest(fn)).Line()
 	}
 	for _, fn := range t.encodersResponse {
-		f.Line().Add(encodeHttpResponse(fn)).Line()
-	}
-
-	if t.state == AppendStrat {
-		return f
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(encodeHttpResponse(fn)).Line()
 	}
 
-	file := NewFile("httpconv")
+	file := t.Info.newFile(HTTPConverterPackage)
 	file.PackageComment(FileHeader)
 	file.PackageComment(`Please, do not change functions names!`)
 	file.Add(f)
@@ -200,12 +178,29 @@ func commonEncoderResponse() *Statement {
 	})
 }
 
-//		func DecodeHTTPCountRequest(_ context.Context, r *http.Request) (interface{}, error) {
-//			var req svc.CountRequest
-//			err := json.NewDecoder(r.Body).Decode(&req)
-//			return req, err
+// Render request decoder, which reads arguments from json body, path, query and headers.
+//
+//		func DecodeHTTPGetUserRequest(_ context.Context, r *http.Request) (interface{}, error) {
+//			var req svc.GetUserRequest
+//			if value := mux.Vars(r)["id"]; value != "" {
+//				value, err := url.PathUnescape(value)
+//				if err != nil {
+//					return nil, err
+//				}
+//				v, err := strconv.ParseInt(value, 10, 64)
+//				if err != nil {
+//					return nil, err
+//				}
+//				req.Id = int(v)
+//			}
+//			if value := r.Header.Get("X-Auth-Token"); value != "" {
+//				req.Token = value
+//			}
+//			return &req, nil
 //		}
+//
 func (t *httpConverterTemplate) decodeHttpRequest(fn *types.Function) *Statement {
+	route := t.routes[fn.Name]
 	return Func().Id(httpDecodeRequestName(fn)).
 		Params(
 			Id("_").Qual(PackagePathContext, "Context"),
@@ -214,16 +209,37 @@ func (t *httpConverterTemplate) decodeHttpRequest(fn *types.Function) *Statement
 		Interface(),
 		Error(),
 	).BlockFunc(func(g *Group) {
-		g.Var().Id("req").Qual(t.Info.ServiceImportPath, requestStructName(fn))
-		g.Err().Op(":=").Qual(PackagePathJson, "NewDecoder").Call(Id("r").Dot("Body")).Dot("Decode").Call(Op("&").Id("req"))
-		g.Return(Id("req"), Err())
+		g.Var().Id("req").Qual(t.Info.importPath(EndpointPackage), requestStructName(fn))
+		if len(route.Body) > 0 {
+			g.If(
+				Err().Op(":=").Qual(PackagePathJson, "NewDecoder").Call(Id("r").Dot("Body")).Dot("Decode").Call(Op("&").Id("req")),
+				Err().Op("!=").Nil(),
+			).Block(
+				Return(Nil(), Err()),
+			)
+		}
+		for _, param := range route.Params {
+			parser := httpParamParser(param.Field.Type)
+			g.If(Id("value").Op(":=").Add(param.source()), Id("value").Op("!=").Lit("")).BlockFunc(func(b *Group) {
+				// Router matches escaped path, so path segments are unescaped here.
+				if param.In == httpParamInPath {
+					b.List(Id("value"), Err()).Op(":=").Qual(PackagePathUrl, "PathUnescape").Call(Id("value"))
+					b.If(Err().Op("!=").Nil()).Block(Return(Nil(), Err()))
+				}
+				b.Add(parser(Id("value"), param.value()))
+			})
+		}
+		g.Return(Op("&").Id("req"), Nil())
 	})
 }
 
 //		func DecodeHTTPCountResponse(_ context.Context, r *http.Response) (interface{}, error) {
+//			if r.StatusCode >= http.StatusBadRequest {
+//				return nil, ErrorDecoder(r)
+//			}
 //			var resp svc.CountResponse
 //			err := json.NewDecoder(r.Body).Decode(&resp)
-//			return resp, err
+//			return &resp, err
 //		}
 func (t *httpConverterTemplate) decodeHttpResponse(fn *types.Function) *Statement {
 	return Func().Id(httpDecodeResponseName(fn)).
@@ -235,9 +251,12 @@ func (t *httpConverterTemplate) decodeHttpResponse(fn *types.Function) *Statemen
 		Error(),
 	).
 		BlockFunc(func(g *Group) {
-			g.Var().Id("resp").Qual(t.Info.ServiceImportPath, responseStructName(fn))
+			g.If(Id("r").Dot("StatusCode").Op(">=").Qual(PackagePathHttp, "StatusBadRequest")).Block(
+				Return(Nil(), Id(httpErrorDecoderName).Call(Id("r"))),
+			)
+			g.Var().Id("resp").Qual(t.Info.importPath(EndpointPackage), responseStructName(fn))
 			g.Err().Op(":=").Qual(PackagePathJson, "NewDecoder").Call(Id("r").Dot("Body")).Dot("Decode").Call(Op("&").Id("resp"))
-			g.Return(Id("resp"), Err())
+			g.Return(Op("&").Id("resp"), Err())
 		})
 }
 
@@ -258,21 +277,128 @@ func encodeHttpResponse(fn *types.Function) *Statement {
 	)
 }
 
-// Render request encoder.
-//		func EncodeHTTPCountRequest(ctx context.Context, r *http.Request, request interface{}) error {
-//			return DefaultRequestEncoder(ctx, r, request)
+// Render request encoder, which writes arguments to path, query, headers and json body.
+// Only arguments, which are not bound to path, query or headers, are sent in body.
+//
+//		func EncodeHTTPGetUserRequest(ctx context.Context, r *http.Request, request interface{}) error {
+//			req := request.(*svc.GetUserRequest)
+//			r.URL.RawPath = strings.TrimSuffix(r.URL.EscapedPath(), "/") + "/users/" + url.PathEscape(strconv.FormatInt(int64(req.Id), 10))
+//			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/") + "/users/" + strconv.FormatInt(int64(req.Id), 10)
+//			r.Header.Set("X-Auth-Token", req.Token)
+//			return nil
+//		}
+//
+//		func EncodeHTTPUpdateUserRequest(ctx context.Context, r *http.Request, request interface{}) error {
+//			req := request.(*svc.UpdateUserRequest)
+//			...
+//			return CommonHTTPRequestEncoder(ctx, r, struct {
+//				User *svc.User `json:"user"`
+//			}{User: req.User})
 //		}
 //
-func encodeHttpRequest(fn *types.Function) *Statement {
+func (t *httpConverterTemplate) encodeHttpRequest(fn *types.Function) *Statement {
+	route := t.routes[fn.Name]
 	return Func().Id(httpEncodeRequestName(fn)).Params(
 		Id("ctx").Qual(PackagePathContext, "Context"),
 		Id("r").Op("*").Qual(PackagePathHttp, "Request"),
 		Id("request").Interface(),
 	).Params(
 		Error(),
-	).Block(
-		Return().Id(commonRequestEncoderName).Call(Id("ctx"), Id("r"), Id("request")),
-	)
+	).BlockFunc(func(g *Group) {
+		if len(route.Params) > 0 {
+			g.Id("req").Op(":=").Id("request").Assert(Op("*").Qual(t.Info.importPath(EndpointPackage), requestStructName(fn)))
+		}
+		// Escaped path is set first, because it is built from escaped path of client.
+		if route.hasParamsIn(httpParamInPath) {
+			g.Id("r").Dot("URL").Dot("RawPath").Op("=").
+				Qual(PackagePathStrings, "TrimSuffix").Call(Id("r").Dot("URL").Dot("EscapedPath").Call(), Lit("/")).Op("+").Add(t.requestPath(route, true))
+		}
+		g.Id("r").Dot("URL").Dot("Path").Op("=").
+			Qual(PackagePathStrings, "TrimSuffix").Call(Id("r").Dot("URL").Dot("Path"), Lit("/")).Op("+").Add(t.requestPath(route, false))
+		var query []httpParam
+		for _, param := range route.Params {
+			switch param.In {
+			case httpParamInQuery:
+				query = append(query, param)
+			case httpParamInHeader:
+				g.Id("r").Dot("Header").Dot("Set").Call(Lit(param.Name), httpParamFormatter(param.Field.Type, param.value()))
+			}
+		}
+		if len(query) > 0 {
+			g.Id("q").Op(":=").Id("r").Dot("URL").Dot("Query").Call()
+			for _, param := range query {
+				g.Id("q").Dot("Set").Call(Lit(param.Name), httpParamFormatter(param.Field.Type, param.value()))
+			}
+			g.Id("r").Dot("URL").Dot("RawQuery").Op("=").Id("q").Dot("Encode").Call()
+		}
+		switch {
+		case len(route.Body) == 0:
+			g.Return(Nil())
+		case len(route.Params) == 0:
+			g.Return().Id(commonRequestEncoderName).Call(Id("ctx"), Id("r"), Id("request"))
+		default:
+			g.Return().Id(commonRequestEncoderName).Call(Id("ctx"), Id("r"), t.requestBody(route))
+		}
+	})
+}
+
+// Renders structure with arguments, which are sent in body.
+//
+//		struct {
+//			User *svc.User `json:"user"`
+//		}{User: req.User}
+//
+func (t *httpConverterTemplate) requestBody(route *httpRoute) *Statement {
+	fields := make([]Code, len(route.Body))
+	values := make(Dict)
+	for i, field := range route.Body {
+		fields[i] = structField(&route.Body[i], t.Info.JSONNaming)
+		values[structFieldName(&field)] = Id("req").Dot(util.ToUpperFirst(field.Name))
+	}
+	return Struct(fields...).Values(values)
+}
+
+// Renders path of request with formatted path parameters, which are escaped, when escape is true.
+//
+//		"/users/" + url.PathEscape(strconv.FormatInt(int64(req.Id), 10))
+//
+func (t *httpConverterTemplate) requestPath(route *httpRoute, escape bool) *Statement {
+	params := make(map[string]httpParam)
+	for _, param := range route.Params {
+		if param.In == httpParamInPath {
+			params[param.Name] = param
+		}
+	}
+	var parts []Code
+	literal := ""
+	for _, segment := range strings.SplitAfter(route.Path, "/") {
+		name := strings.TrimSuffix(segment, "/")
+		param, ok := params[strings.TrimSuffix(strings.TrimPrefix(name, "{"), "}")]
+		if !ok || !strings.HasPrefix(name, "{") {
+			literal += segment
+			continue
+		}
+		if literal != "" {
+			parts = append(parts, Lit(literal))
+		}
+		value := httpParamFormatter(param.Field.Type, param.value())
+		if escape {
+			value = Qual(PackagePathUrl, "PathEscape").Call(value)
+		}
+		parts = append(parts, value)
+		literal = segment[len(name):]
+	}
+	if literal != "" {
+		parts = append(parts, Lit(literal))
+	}
+	s := &Statement{}
+	for i, part := range parts {
+		if i > 0 {
+			s.Op("+")
+		}
+		s.Add(part)
+	}
+	return s
 }
 
 func httpDecodeRequestName(f *types.Function) string {
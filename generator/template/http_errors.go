@@ -0,0 +1,228 @@
+package template
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
+)
+
+const (
+	httpErrorEncoderName = "ErrorEncoder"
+	httpErrorDecoderName = "ErrorDecoder"
+	problemStructName    = "Problem"
+	problemContentType   = "application/problem+json"
+	// Type of problem is prefix with code of error, e.g. `urn:problem-type:not_found`.
+	problemTypePrefix = "urn:problem-type:"
+)
+
+type httpErrorsTemplate struct {
+	Info *GenerationInfo
+}
+
+func NewHttpErrorsTemplate(info *GenerationInfo) Template {
+	return &httpErrorsTemplate{
+		Info: info,
+	}
+}
+
+// Render http error encoder and decoder, which write errors as RFC 7807 problem documents
+// with http statuses of error codes and restore errors from them.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//		package httpconv
+//
+//		const ProblemContentType = "application/problem+json"
+//
+//		var httpStatuses = map[svcerror.Code]int{
+//			svcerror.Unknown:         http.StatusInternalServerError,
+//			svcerror.InvalidArgument: http.StatusBadRequest,
+//			...
+//		}
+//
+//		var httpErrorCodes = map[int]svcerror.Code{
+//			http.StatusInternalServerError: svcerror.Unknown,
+//			http.StatusBadRequest:          svcerror.InvalidArgument,
+//			...
+//		}
+//
+//		type Problem struct {
+//			Type     string        `json:"type"`
+//			Title    string        `json:"title"`
+//			Status   int           `json:"status"`
+//			Detail   string        `json:"detail,omitempty"`
+//			Instance string        `json:"instance,omitempty"`
+//			Code     svcerror.Code `json:"code"`
+//		}
+//
+//		func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+//			code := svcerror.CodeOf(err)
+//			status, ok := httpStatuses[code]
+//			if !ok {
+//				status = http.StatusInternalServerError
+//			}
+//			w.Header().Set("Content-Type", ProblemContentType)
+//			w.WriteHeader(status)
+//			json.NewEncoder(w).Encode(Problem{
+//				Type:     "urn:problem-type:" + string(code),
+//				Title:    http.StatusText(status),
+//				Status:   status,
+//				Detail:   err.Error(),
+//				Instance: newProblemInstance(),
+//				Code:     code,
+//			})
+//		}
+//
+//		func newProblemInstance() string {
+//			var b [16]byte
+//			if _, err := rand.Read(b[:]); err != nil {
+//				return ""
+//			}
+//			b[6] = b[6]&0x0f | 0x40
+//			b[8] = b[8]&0x3f | 0x80
+//			return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+//		}
+//
+//		func ErrorDecoder(r *http.Response) error {
+//			code, ok := httpErrorCodes[r.StatusCode]
+//			if !ok {
+//				code = svcerror.Unknown
+//			}
+//			message := r.Status
+//			if strings.HasPrefix(r.Header.Get("Content-Type"), ProblemContentType) {
+//				var p Problem
+//				if err := json.NewDecoder(r.Body).Decode(&p); err == nil {
+//					if p.Code != "" {
+//						code = p.Code
+//					}
+//					message = p.Detail
+//				}
+//			}
+//			return svcerror.New(code, message)
+//		}
+//
+func (t *httpErrorsTemplate) Render() write_strategy.Renderer {
+	errPkg := t.Info.importPath(ErrorPackage)
+	f := t.Info.newFile(HTTPConverterPackage)
+	f.PackageComment(FileHeader)
+	f.PackageComment(`Please, do not edit.`)
+
+	f.Comment("Content type of RFC 7807 problem documents.").
+		Line().Const().Id("ProblemContentType").Op("=").Lit(problemContentType)
+	f.Line()
+	f.Comment("Http statuses of error codes.").
+		Line().Var().Id("httpStatuses").Op("=").Map(Qual(errPkg, errorCodeType)).Int().Values(DictFunc(func(d Dict) {
+		for _, code := range errorCodes {
+			d[Qual(errPkg, code.Name)] = Qual(PackagePathHttp, code.HTTPStatus)
+		}
+	}))
+	f.Line()
+	f.Comment("Error codes of http statuses, when response is not a problem document.").
+		Line().Var().Id("httpErrorCodes").Op("=").Map(Int()).Qual(errPkg, errorCodeType).Values(DictFunc(func(d Dict) {
+		seen := make(map[string]bool)
+		for _, code := range errorCodes {
+			if seen[code.HTTPStatus] {
+				continue
+			}
+			seen[code.HTTPStatus] = true
+			d[Qual(PackagePathHttp, code.HTTPStatus)] = Qual(errPkg, code.Name)
+		}
+	}))
+	f.Line()
+
+	f.Comment(problemStructName+" is RFC 7807 problem document with code of error as extension member.").
+		Line().Type().Id(problemStructName).Struct(
+		Id("Type").String().Tag(map[string]string{"json": "type"}),
+		Id("Title").String().Tag(map[string]string{"json": "title"}),
+		Id("Status").Int().Tag(map[string]string{"json": "status"}),
+		Id("Detail").String().Tag(map[string]string{"json": "detail,omitempty"}),
+		Id("Instance").String().Tag(map[string]string{"json": "instance,omitempty"}),
+		Id("Code").Qual(errPkg, errorCodeType).Tag(map[string]string{"json": "code"}),
+	)
+	f.Line()
+
+	f.Comment(httpErrorEncoderName+" writes error as problem document with http status of error code.").
+		Line().Comment("Every problem has unique instance, so it can be found in logs.").
+		Line().Func().Id(httpErrorEncoderName).Params(
+		Id("_").Qual(PackagePathContext, "Context"),
+		Err().Error(),
+		Id("w").Qual(PackagePathHttp, "ResponseWriter"),
+	).BlockFunc(func(g *Group) {
+		g.Id("code").Op(":=").Qual(errPkg, errorCodeOfName).Call(Err())
+		g.List(Id("status"), Id("ok")).Op(":=").Id("httpStatuses").Index(Id("code"))
+		g.If(Op("!").Id("ok")).Block(
+			Id("status").Op("=").Qual(PackagePathHttp, "StatusInternalServerError"),
+		)
+		g.Id("w").Dot("Header").Call().Dot("Set").Call(Lit("Content-Type"), Id("ProblemContentType"))
+		g.Id("w").Dot("WriteHeader").Call(Id("status"))
+		g.Qual(PackagePathJson, "NewEncoder").Call(Id("w")).Dot("Encode").Call(Id(problemStructName).Values(Dict{
+			Id("Type"):     Lit(problemTypePrefix).Op("+").String().Call(Id("code")),
+			Id("Title"):    Qual(PackagePathHttp, "StatusText").Call(Id("status")),
+			Id("Status"):   Id("status"),
+			Id("Detail"):   Err().Dot("Error").Call(),
+			Id("Instance"): Id("newProblemInstance").Call(),
+			Id("Code"):     Id("code"),
+		}))
+	})
+	f.Line()
+
+	f.Comment("Returns random uuid urn or empty string, when random source fails.").
+		Line().Func().Id("newProblemInstance").Params().String().Block(
+		Var().Id("b").Index(Lit(16)).Byte(),
+		If(List(Id("_"), Err()).Op(":=").Qual(PackagePathCryptoRand, "Read").Call(Id("b").Index(Op(":"))), Err().Op("!=").Nil()).Block(
+			Return(Lit("")),
+		),
+		Id("b").Index(Lit(6)).Op("=").Id("b").Index(Lit(6)).Op("&").Op("0x0f").Op("|").Op("0x40"),
+		Id("b").Index(Lit(8)).Op("=").Id("b").Index(Lit(8)).Op("&").Op("0x3f").Op("|").Op("0x80"),
+		Return(Qual(PackagePathFmt, "Sprintf").Call(
+			Lit("urn:uuid:%x-%x-%x-%x-%x"),
+			Id("b").Index(Lit(0), Lit(4)),
+			Id("b").Index(Lit(4), Lit(6)),
+			Id("b").Index(Lit(6), Lit(8)),
+			Id("b").Index(Lit(8), Lit(10)),
+			Id("b").Index(Lit(10), Empty()),
+		)),
+	)
+	f.Line()
+
+	f.Comment(httpErrorDecoderName + " restores error from problem document of response with error status.").
+		Line().Comment("Error code of status is used, when response is not a problem document.").
+		Line().Func().Id(httpErrorDecoderName).Params(
+		Id("r").Op("*").Qual(PackagePathHttp, "Response"),
+	).Error().BlockFunc(func(g *Group) {
+		g.List(Id("code"), Id("ok")).Op(":=").Id("httpErrorCodes").Index(Id("r").Dot("StatusCode"))
+		g.If(Op("!").Id("ok")).Block(
+			Id("code").Op("=").Qual(errPkg, errorCodes[0].Name),
+		)
+		g.Id("message").Op(":=").Id("r").Dot("Status")
+		g.If(Qual(PackagePathStrings, "HasPrefix").Call(
+			Id("r").Dot("Header").Dot("Get").Call(Lit("Content-Type")),
+			Id("ProblemContentType"),
+		)).Block(
+			Var().Id("p").Id(problemStructName),
+			If(
+				Err().Op(":=").Qual(PackagePathJson, "NewDecoder").Call(Id("r").Dot("Body")).Dot("Decode").Call(Op("&").Id("p")),
+				Err().Op("==").Nil(),
+			).Block(
+				If(Id("p").Dot("Code").Op("!=").Lit("")).Block(
+					Id("code").Op("=").Id("p").Dot("Code"),
+				),
+				Id("message").Op("=").Id("p").Dot("Detail"),
+			),
+		)
+		g.Return(Qual(errPkg, "New").Call(Id("code"), Id("message")))
+	})
+
+	return f
+}
+
+func (t *httpErrorsTemplate) DefaultPath() string {
+	return t.Info.filePath(HTTPErrorsFile)
+}
+
+func (t *httpErrorsTemplate) Prepare() error {
+	return nil
+}
+
+func (t *httpErrorsTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
@@ -0,0 +1,324 @@
+package template
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+const (
+	// Method tags of http transport.
+	//
+	//		// @http-method GET
+	//		// @http-path /users/{id}
+	//		// @http-query limit, offset:skip
+	//		// @http-header token:X-Auth-Token
+	//		GetUser(ctx context.Context, id int, limit int, offset int, token string) (user *User, err error)
+	//
+	httpMethodTag = "http-method"
+	httpPathTag   = "http-path"
+	httpQueryTag  = "http-query"
+	httpHeaderTag = "http-header"
+)
+
+// Location of http parameter.
+type httpParamIn string
+
+const (
+	httpParamInPath   httpParamIn = "path"
+	httpParamInQuery  httpParamIn = "query"
+	httpParamInHeader httpParamIn = "header"
+)
+
+// Argument of method, which is bound to path segment, query parameter or header.
+type httpParam struct {
+	Field types.Variable
+	// Name of parameter in http request.
+	Name string
+	In   httpParamIn
+}
+
+// Route of method in http transport.
+type httpRoute struct {
+	Method string
+	// Path template, e.g. `/users/{id}`.
+	Path   string
+	Params []httpParam
+	// Arguments, which are sent in json body.
+	Body []types.Variable
+}
+
+var httpMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// Reads route of method from its tags. By default method is `POST /<method_name>`
+// and all arguments are sent in body.
+func newHTTPRoute(fn *types.Function) (*httpRoute, error) {
+	route := &httpRoute{
+		Method: http.MethodPost,
+		Path:   "/" + util.ToURLSnakeCase(fn.Name),
+	}
+	if tags := util.FetchTags(fn.Docs, TagMark+httpMethodTag); len(tags) > 0 {
+		route.Method = strings.ToUpper(tags[0])
+		if !util.IsInStringSlice(route.Method, httpMethods) {
+			return nil, fmt.Errorf("%s: unknown http method %s", fn.Name, tags[0])
+		}
+	}
+	if tags := util.FetchTags(fn.Docs, TagMark+httpPathTag); len(tags) > 0 {
+		route.Path = tags[0]
+		if !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("%s: http path %s should start with /", fn.Name, route.Path)
+		}
+	}
+
+	args := removeContextIfFirst(fn.Args)
+	bound := make(map[string]httpParam)
+	bind := func(arg, name string, in httpParamIn) error {
+		field := findVariable(args, arg)
+		if field == nil {
+			return fmt.Errorf("%s: %s %s is not an argument", fn.Name, in, arg)
+		}
+		if _, ok := bound[arg]; ok {
+			return fmt.Errorf("%s: argument %s is bound twice", fn.Name, arg)
+		}
+		if httpParamParser(field.Type) == nil {
+			return fmt.Errorf("%s: type %s of %s %s is not supported", fn.Name, field.Type.String(), in, arg)
+		}
+		param := httpParam{Field: *field, Name: name, In: in}
+		bound[arg] = param
+		route.Params = append(route.Params, param)
+		return nil
+	}
+	for _, segment := range pathParams(route.Path) {
+		if err := bind(segment, segment, httpParamInPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range util.FetchTags(fn.Docs, TagMark+httpQueryTag) {
+		arg, name := splitHTTPParamTag(tag)
+		if err := bind(arg, name, httpParamInQuery); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range util.FetchTags(fn.Docs, TagMark+httpHeaderTag) {
+		arg, name := splitHTTPParamTag(tag)
+		if err := bind(arg, name, httpParamInHeader); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range args {
+		if _, ok := bound[arg.Name]; !ok {
+			route.Body = append(route.Body, arg)
+		}
+	}
+	if len(route.Body) > 0 && (route.Method == http.MethodGet || route.Method == http.MethodHead) {
+		return nil, fmt.Errorf("%s: %s request has no body, argument %s should be bound to path, query or header", fn.Name, route.Method, route.Body[0].Name)
+	}
+	return route, nil
+}
+
+// Returns routes of all interface methods by method name.
+func newHTTPRoutes(iface *types.Interface) (map[string]*httpRoute, error) {
+	routes := make(map[string]*httpRoute)
+	for _, fn := range iface.Methods {
+		route, err := newHTTPRoute(fn)
+		if err != nil {
+			return nil, err
+		}
+		routes[fn.Name] = route
+	}
+	return routes, nil
+}
+
+// Tag value `arg:name` binds argument to parameter with another name.
+func splitHTTPParamTag(tag string) (arg, name string) {
+	if i := strings.Index(tag, ":"); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, tag
+}
+
+// Returns names of path segments in braces.
+func pathParams(path string) (params []string) {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"))
+		}
+	}
+	return
+}
+
+func findVariable(vars []types.Variable, name string) *types.Variable {
+	for i := range vars {
+		if vars[i].Name == name {
+			return &vars[i]
+		}
+	}
+	return nil
+}
+
+// Returns statement, which reads value of parameter from request r.
+//
+//		mux.Vars(r)["id"]
+//		r.URL.Query().Get("limit")
+//		r.Header.Get("X-Auth-Token")
+//
+func (p httpParam) source() *Statement {
+	switch p.In {
+	case httpParamInPath:
+		return Qual(PackagePathGorillaMux, "Vars").Call(Id("r")).Index(Lit(p.Name))
+	case httpParamInQuery:
+		return Id("r").Dot("URL").Dot("Query").Call().Dot("Get").Call(Lit(p.Name))
+	}
+	return Id("r").Dot("Header").Dot("Get").Call(Lit(p.Name))
+}
+
+// Reports whether some arguments are bound to location in.
+func (r *httpRoute) hasParamsIn(in httpParamIn) bool {
+	for _, param := range r.Params {
+		if param.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns value of parameter in request exchange.
+//
+//		req.Id
+//
+func (p httpParam) value() *Statement {
+	return Id("req").Dot(util.ToUpperFirst(p.Field.Name))
+}
+
+// Returns parser of string value to type or nil, when type is not supported.
+// Parser assigns parsed value to target and returns error, when value is invalid.
+//
+//		v, err := strconv.ParseInt(value, 10, 64)
+//		if err != nil {
+//			return nil, err
+//		}
+//		req.Limit = int(v)
+//
+func httpParamParser(t types.Type) func(value, target *Statement) *Statement {
+	var name string
+	switch f := t.(type) {
+	case types.TName:
+		name = f.TypeName
+	case types.TImport:
+		if f.Import == nil {
+			return httpParamParser(f.Next)
+		}
+		next, ok := f.Next.(types.TName)
+		if !ok {
+			return nil
+		}
+		name = f.Import.Package + "." + next.TypeName
+	default:
+		return nil
+	}
+	// Parsed value is converted, when parser returns another type.
+	parse := func(call func(value *Statement) *Statement, convert string) func(value, target *Statement) *Statement {
+		return func(value, target *Statement) *Statement {
+			v := Id("v")
+			if convert != "" {
+				v = Id(convert).Call(Id("v"))
+			}
+			return List(Id("v"), Err()).Op(":=").Add(call(value)).
+				Line().If(Err().Op("!=").Nil()).Block(Return(Nil(), Err())).
+				Line().Add(target).Op("=").Add(v)
+		}
+	}
+	switch name {
+	case "string":
+		return func(value, target *Statement) *Statement {
+			return target.Op("=").Add(value)
+		}
+	case "bool":
+		return parse(func(value *Statement) *Statement {
+			return Qual(PackagePathStrconv, "ParseBool").Call(value)
+		}, "")
+	case "int", "int8", "int16", "int32", "int64":
+		return parse(func(value *Statement) *Statement {
+			return Qual(PackagePathStrconv, "ParseInt").Call(value, Lit(10), Lit(intBitSize(name)))
+		}, convertTo(name, "int64"))
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return parse(func(value *Statement) *Statement {
+			return Qual(PackagePathStrconv, "ParseUint").Call(value, Lit(10), Lit(intBitSize(name)))
+		}, convertTo(name, "uint64"))
+	case "float32", "float64":
+		return parse(func(value *Statement) *Statement {
+			return Qual(PackagePathStrconv, "ParseFloat").Call(value, Lit(intBitSize(name)))
+		}, convertTo(name, "float64"))
+	case "time.Time":
+		return parse(func(value *Statement) *Statement {
+			return Qual(PackagePathTime, "Parse").Call(Qual(PackagePathTime, "RFC3339"), value)
+		}, "")
+	case "time.Duration":
+		return parse(func(value *Statement) *Statement {
+			return Qual(PackagePathTime, "ParseDuration").Call(value)
+		}, "")
+	}
+	return nil
+}
+
+// Returns formatter of value of supported type to string.
+//
+//		strconv.FormatInt(int64(req.Limit), 10)
+//
+func httpParamFormatter(t types.Type, value *Statement) *Statement {
+	var name string
+	switch f := t.(type) {
+	case types.TName:
+		name = f.TypeName
+	case types.TImport:
+		if f.Import == nil {
+			return httpParamFormatter(f.Next, value)
+		}
+		name = f.Import.Package + "." + f.Next.(types.TName).TypeName
+	}
+	switch name {
+	case "string":
+		return value
+	case "bool":
+		return Qual(PackagePathStrconv, "FormatBool").Call(value)
+	case "int", "int8", "int16", "int32", "int64":
+		return Qual(PackagePathStrconv, "FormatInt").Call(Int64().Call(value), Lit(10))
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return Qual(PackagePathStrconv, "FormatUint").Call(Uint64().Call(value), Lit(10))
+	case "float32", "float64":
+		return Qual(PackagePathStrconv, "FormatFloat").Call(Float64().Call(value), LitRune('g'), Lit(-1), Lit(intBitSize(name)))
+	case "time.Time":
+		return value.Dot("Format").Call(Qual(PackagePathTime, "RFC3339Nano"))
+	case "time.Duration":
+		return value.Dot("String").Call()
+	}
+	panic(fmt.Sprintf("unsupported type of http parameter %s", t.String()))
+}
+
+// Returns type, which parsed value should be converted to, or empty string.
+func convertTo(name, parsed string) string {
+	if name == parsed {
+		return ""
+	}
+	return name
+}
+
+// Returns bit size of numeric type, `int` and `uint` are 64 bits.
+func intBitSize(name string) int {
+	switch strings.TrimLeft(name, "uintfloat") {
+	case "8":
+		return 8
+	case "16":
+		return 16
+	case "32":
+		return 32
+	}
+	return 64
+}
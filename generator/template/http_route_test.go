@@ -0,0 +1,68 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func httpRouteTestInfo(t *testing.T, docs ...string) *GenerationInfo {
+	id := types.Variable{Base: types.Base{Name: "id"}, Type: types.TName{TypeName: "int"}}
+	since := types.Variable{Base: types.Base{Name: "since"}, Type: types.TImport{Import: &types.Import{Package: PackagePathTime}, Next: types.TName{TypeName: "Time"}}}
+	token := types.Variable{Base: types.Base{Name: "token"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, "/tmp/out", id, since, token)
+	info.Iface.Methods[0].Docs = docs
+	return info
+}
+
+func TestHTTPRoute(t *testing.T) {
+	info := httpRouteTestInfo(t,
+		"// @http-method get",
+		"// @http-path /symbols/{id}/count",
+		"// @http-query since:from",
+		"// @http-header token:X-Auth-Token",
+	)
+	// All arguments are bound, so request has no body.
+	testRender(t, []renderCase{
+		{"server", NewHttpServerTemplate(info), []string{
+			`router := mux.NewRouter().UseEncodedPath()`,
+			`router.Methods("GET").Path("/symbols/{id}/count").Handler(`,
+		}},
+		{"converters", NewHttpConverterTemplate(info), []string{
+			"if value := mux.Vars(r)[\"id\"]; value != \"\" {\n\t\tvalue, err := url.PathUnescape(value)",
+			"req.Id = int(v)",
+			"r.URL.RawPath = strings.TrimSuffix(r.URL.EscapedPath(), \"/\") + \"/symbols/\" + url.PathEscape(strconv.FormatInt(int64(req.Id), 10)) + \"/count\"",
+			"r.URL.Path = strings.TrimSuffix(r.URL.Path, \"/\") + \"/symbols/\" + strconv.FormatInt(int64(req.Id), 10) + \"/count\"",
+			"q.Set(\"from\", req.Since.Format(time.RFC3339Nano))",
+			"r.Header.Set(\"X-Auth-Token\", req.Token)\n\tq := r.URL.Query()",
+			"r.URL.RawQuery = q.Encode()\n\treturn nil\n}",
+		}},
+	})
+
+	// Bound arguments are not sent in body.
+	info = httpRouteTestInfo(t,
+		"// @http-method put",
+		"// @http-path /symbols/{id}",
+	)
+	testRender(t, []renderCase{
+		{"converters with body", NewHttpConverterTemplate(info), []string{
+			"if err := json.NewDecoder(r.Body).Decode(&req); err != nil {",
+			"return CommonHTTPRequestEncoder(ctx, r, struct {\n\t\tSince time.Time `json:\"since\"`\n\t\tToken string    `json:\"token\"`\n\t}{\n\t\tSince: req.Since,\n\t\tToken: req.Token,\n\t})",
+		}},
+	})
+}
+
+func TestHTTPRouteErrors(t *testing.T) {
+	for _, docs := range [][]string{
+		{"// @http-method FETCH"},
+		{"// @http-path /symbols/{symbol}"},
+		{"// @http-query id", "// @http-header id"},
+		// Get request has no body.
+		{"// @http-method GET", "// @http-query id"},
+	} {
+		info := httpRouteTestInfo(t, docs...)
+		if _, err := newHTTPRoute(info.Iface.Methods[0]); err == nil {
+			t.Errorf("%v: error is expected", docs)
+		}
+	}
+}
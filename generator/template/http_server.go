@@ -1,15 +1,14 @@
 package template
 
 import (
-	"path/filepath"
-
 	. "github.com/dave/jennifer/jen"
 	"github.com/devimteam/microgen/generator/write_strategy"
 	"github.com/devimteam/microgen/util"
 )
 
 type httpServerTemplate struct {
-	Info *GenerationInfo
+	Info   *GenerationInfo
+	routes map[string]*httpRoute
 }
 
 func NewHttpServerTemplate(info *GenerationInfo) Template {
@@ -19,7 +18,7 @@ func NewHttpServerTemplate(info *GenerationInfo) Template {
 }
 
 func (t *httpServerTemplate) DefaultPath() string {
-	return "./transport/http/server.go"
+	return t.Info.filePath(HTTPServerFile)
 }
 
 func (t *httpServerTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
@@ -30,11 +29,13 @@ func (t *httpServerTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
 }
 
 func (t *httpServerTemplate) Prepare() error {
-	tags := util.FetchTags(t.Info.Iface.Docs, TagMark+ForceTag)
+	tags := t.Info.ForceTags
 	if util.IsInStringSlice("http", tags) || util.IsInStringSlice("http-server", tags) {
 		t.Info.Force = true
 	}
-	return nil
+	var err error
+	t.routes, err = newHTTPRoutes(t.Info.Iface)
+	return err
 }
 
 // Render http server constructor.
@@ -46,58 +47,62 @@ func (t *httpServerTemplate) Prepare() error {
 //			svc "github.com/devimteam/microgen/example/svc"
 //			http2 "github.com/devimteam/microgen/example/svc/transport/converter/http"
 //			http "github.com/go-kit/kit/transport/http"
+//			mux "github.com/gorilla/mux"
 //			http1 "net/http"
 //		)
 //
 //		func NewHTTPHandler(endpoints *svc.Endpoints, opts ...http.ServerOption) http1.Handler {
-//			handler := http1.NewServeMux()
-//			handler.Handle("/test_case", http.NewServer(
+//			opts = append([]http.ServerOption{http.ServerErrorEncoder(http2.ErrorEncoder)}, opts...)
+//			router := mux.NewRouter().UseEncodedPath()
+//			router.Methods("POST").Path("/test_case").Handler(http.NewServer(
 //				endpoints.TestCaseEndpoint,
 //				http2.DecodeHTTPTestCaseRequest,
 //				http2.EncodeHTTPTestCaseResponse,
 //				opts...))
-//			handler.Handle("/empty_req", http.NewServer(
-//				endpoints.EmptyReqEndpoint,
-//				http2.DecodeHTTPEmptyReqRequest,
-//				http2.EncodeHTTPEmptyReqResponse,
-//				opts...))
-//			handler.Handle("/empty_resp", http.NewServer(
-//				endpoints.EmptyRespEndpoint,
-//				http2.DecodeHTTPEmptyRespRequest,
-//				http2.EncodeHTTPEmptyRespResponse,
+//			router.Methods("GET").Path("/users/{id}").Handler(http.NewServer(
+//				endpoints.GetUserEndpoint,
+//				http2.DecodeHTTPGetUserRequest,
+//				http2.EncodeHTTPGetUserResponse,
 //				opts...))
-//			return handler
+//			return router
 //		}
 //
 func (t *httpServerTemplate) Render() write_strategy.Renderer {
-	f := NewFile("transporthttp")
+	f := t.Info.newFile(HTTPTransportPackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
 	f.Func().Id("NewHTTPHandler").Params(
-		Id("endpoints").Op("*").Qual(t.Info.ServiceImportPath, "Endpoints"),
+		Id("endpoints").Op("*").Qual(t.Info.importPath(EndpointPackage), endpointsStructName(t.Info)),
+		tracingParams(t.Info),
 		Id("opts").Op("...").Qual(PackagePathGoKitTransportHTTP, "ServerOption"),
 	).Params(
 		Qual(PackagePathHttp, "Handler"),
 	).BlockFunc(func(g *Group) {
-		g.Id("handler").Op(":=").Qual(PackagePathHttp, "NewServeMux").Call()
+		g.Id("opts").Op("=").Append(Index().Qual(PackagePathGoKitTransportHTTP, "ServerOption").Values(
+			Qual(PackagePathGoKitTransportHTTP, "ServerErrorEncoder").Call(Qual(pathToHttpConverter(t.Info), httpErrorEncoderName)),
+		), Id("opts").Op("..."))
+		// Path parameters are matched escaped, so they may contain slashes.
+		g.Id("router").Op(":=").Qual(PackagePathGorillaMux, "NewRouter").Call().Dot("UseEncodedPath").Call()
 		for _, fn := range t.Info.Iface.Methods {
-			g.Id("handler").Dot("Handle").Call(
-				Lit("/"+util.ToURLSnakeCase(fn.Name)),
+			route := t.routes[fn.Name]
+			g.Id("router").Dot("Methods").Call(Lit(route.Method)).Dot("Path").Call(Lit(route.Path)).Dot("Handler").Call(
 				Qual(PackagePathGoKitTransportHTTP, "NewServer").Call(
 					Line().Id("endpoints").Dot(endpointStructName(fn.Name)),
-					Line().Qual(pathToHttpConverter(t.Info.ServiceImportPath), httpDecodeRequestName(fn)),
-					Line().Qual(pathToHttpConverter(t.Info.ServiceImportPath), httpEncodeResponseName(fn)),
-					Line().Id("opts").Op("..."),
+					Line().Qual(pathToHttpConverter(t.Info), httpDecodeRequestName(fn)),
+					Line().Qual(pathToHttpConverter(t.Info), httpEncodeResponseName(fn)),
+					Line().Add(tracingOpts(t.Info, Qual(PackagePathGoKitTransportHTTP, "ServerBefore").Call(
+						Qual(PackagePathGoKitOpenTracing, "HTTPToContext").Call(Id(tracerVarName), Lit(fn.Name), Id(loggerVarName)),
+					))),
 				),
 			)
 		}
-		g.Return(Id("handler"))
+		g.Return(Id("router"))
 	})
 
 	return f
 }
 
-func pathToHttpConverter(servicePath string) string {
-	return filepath.Join(servicePath, "transport/converter/http")
+func pathToHttpConverter(info *GenerationInfo) string {
+	return info.importPath(HTTPConverterPackage)
 }
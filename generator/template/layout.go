@@ -0,0 +1,404 @@
+package template
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/util"
+)
+
+const (
+	LegacyLayoutName = "legacy"
+	AddsvcLayoutName = "addsvc"
+)
+
+// Kind of package, where generated files are placed.
+type PackageKind int
+
+const (
+	// Endpoints and exchanges.
+	EndpointPackage PackageKind = iota + 1
+	// Service middlewares.
+	MiddlewarePackage
+	GRPCTransportPackage
+	HTTPTransportPackage
+	// Converters between protobuf types and exchanges.
+	GRPCConverterPackage
+	// Converters between http requests and exchanges.
+	HTTPConverterPackage
+	MainPackage
+	// Protobuf definitions, it is not go package.
+	ProtoPackage
+	// Errors with codes, which are sent through transports.
+	ErrorPackage
+	// Mock of service for tests.
+	MockPackage
+)
+
+// Names of package kinds, used in configuration.
+var packageKindNames = map[string]PackageKind{
+	"endpoint":       EndpointPackage,
+	"middleware":     MiddlewarePackage,
+	"grpc-transport": GRPCTransportPackage,
+	"http-transport": HTTPTransportPackage,
+	"grpc-converter": GRPCConverterPackage,
+	"http-converter": HTTPConverterPackage,
+	"error":          ErrorPackage,
+	"mock":           MockPackage,
+}
+
+// Returns package kind by its name in configuration.
+func PackageKindByName(name string) (PackageKind, bool) {
+	kind, ok := packageKindNames[name]
+	return kind, ok
+}
+
+// Kind of generated file.
+type FileKind int
+
+const (
+	EndpointsFile FileKind = iota + 1
+	ExchangesFile
+	MiddlewareFile
+	LoggingFile
+	RecoveringFile
+	MetricsFile
+	TracingFile
+	GRPCServerFile
+	GRPCClientFile
+	GRPCEndpointConvertersFile
+	GRPCTypeConvertersFile
+	HTTPServerFile
+	HTTPClientFile
+	HTTPConvertersFile
+	MainFile
+	ProtoFile
+	// OpenAPI document of http transport.
+	OpenAPIFile
+	// Field numbers of messages in proto file.
+	ProtoLockFile
+	ErrorsFile
+	GRPCErrorsFile
+	HTTPErrorsFile
+	MockFile
+	// Tests of transports, which use mock of service.
+	HTTPTestFile
+	GRPCTestFile
+)
+
+// Package of every kind of file. It does not depend on layout.
+var filePackages = map[FileKind]PackageKind{
+	EndpointsFile:              EndpointPackage,
+	ExchangesFile:              EndpointPackage,
+	MiddlewareFile:             MiddlewarePackage,
+	LoggingFile:                MiddlewarePackage,
+	RecoveringFile:             MiddlewarePackage,
+	MetricsFile:                MiddlewarePackage,
+	TracingFile:                MiddlewarePackage,
+	GRPCServerFile:             GRPCTransportPackage,
+	GRPCClientFile:             GRPCTransportPackage,
+	GRPCEndpointConvertersFile: GRPCConverterPackage,
+	GRPCTypeConvertersFile:     GRPCConverterPackage,
+	HTTPServerFile:             HTTPTransportPackage,
+	HTTPClientFile:             HTTPTransportPackage,
+	HTTPConvertersFile:         HTTPConverterPackage,
+	MainFile:                   MainPackage,
+	ProtoFile:                  ProtoPackage,
+	OpenAPIFile:                HTTPTransportPackage,
+	ProtoLockFile:              ProtoPackage,
+	ErrorsFile:                 ErrorPackage,
+	GRPCErrorsFile:             GRPCTransportPackage,
+	HTTPErrorsFile:             HTTPConverterPackage,
+	MockFile:                   MockPackage,
+	HTTPTestFile:               HTTPTransportPackage,
+	GRPCTestFile:               GRPCTransportPackage,
+}
+
+// Layout describes, where generated files are placed and how their packages are named.
+type Layout interface {
+	// Name of layout, which is used to choose it.
+	Name() string
+	// Returns directory of package, relative to output directory, and name of package.
+	Package(info *GenerationInfo, kind PackageKind) (dir string, name string)
+	// Returns name of file in its package.
+	FileName(info *GenerationInfo, kind FileKind) string
+	// Returns name of structure with all service endpoints.
+	EndpointsStructName(info *GenerationInfo) string
+}
+
+// Returns layout by its name. Empty name means legacy layout.
+func LayoutByName(name string) (Layout, error) {
+	switch name {
+	case "", LegacyLayoutName:
+		return legacyLayout{}, nil
+	case AddsvcLayoutName:
+		return addsvcLayout{}, nil
+	}
+	return nil, fmt.Errorf("unknown layout %s, available layouts: %s", name, strings.Join(LayoutNames(), ", "))
+}
+
+// Returns names of all available layouts.
+func LayoutNames() []string {
+	return []string{LegacyLayoutName, AddsvcLayoutName}
+}
+
+// Layout of previous microgen versions: endpoints and exchanges are placed in output directory,
+// other files in subpackages.
+//
+//		./endpoints.go
+//		./exchanges.go
+//		./middleware/logging.go
+//		./transport/grpc/server.go
+//		./transport/converter/protobuf/endpoint_converters.go
+//		./cmd/string_service/main.go
+//		./string_service.proto
+//
+// When package contains several services, files in output directory are prefixed with namespace
+// and subpackages are placed in namespace directory.
+type legacyLayout struct{}
+
+func (legacyLayout) Name() string {
+	return LegacyLayoutName
+}
+
+func (legacyLayout) Package(info *GenerationInfo, kind PackageKind) (string, string) {
+	switch kind {
+	case EndpointPackage:
+		return "", info.ServiceImportPackageName
+	case MiddlewarePackage:
+		return filepath.Join(info.Namespace, "middleware"), "middleware"
+	case GRPCTransportPackage:
+		return filepath.Join(info.Namespace, "transport", "grpc"), "transportgrpc"
+	case HTTPTransportPackage:
+		return filepath.Join(info.Namespace, "transport", "http"), "transporthttp"
+	case GRPCConverterPackage:
+		return filepath.Join(info.Namespace, "transport", "converter", "protobuf"), "protobuf"
+	case HTTPConverterPackage:
+		return filepath.Join(info.Namespace, "transport", "converter", "http"), "httpconv"
+	case MainPackage:
+		return filepath.Join("cmd", util.ToSnakeCase(info.Iface.Name)), "main"
+	case ProtoPackage:
+		return "", protoPackageName(info)
+	case ErrorPackage:
+		return filepath.Join(info.Namespace, "svcerror"), "svcerror"
+	case MockPackage:
+		return filepath.Join(info.Namespace, servicePrefix(info)+"mock"), servicePrefix(info) + "mock"
+	}
+	panic(fmt.Sprintf("unknown package kind %d", kind))
+}
+
+func (legacyLayout) FileName(info *GenerationInfo, kind FileKind) string {
+	switch kind {
+	case EndpointsFile:
+		return namespaced(info, "endpoints.go")
+	case ExchangesFile:
+		return namespaced(info, "exchanges.go")
+	case MiddlewareFile:
+		return "middleware.go"
+	case LoggingFile:
+		return "logging.go"
+	case RecoveringFile:
+		return "recovering.go"
+	case MetricsFile:
+		return "metrics.go"
+	case TracingFile:
+		return "tracing.go"
+	case GRPCServerFile, HTTPServerFile:
+		return "server.go"
+	case GRPCClientFile, HTTPClientFile:
+		return "client.go"
+	case GRPCEndpointConvertersFile:
+		return "endpoint_converters.go"
+	case GRPCTypeConvertersFile:
+		return "type_converters.go"
+	case HTTPConvertersFile:
+		return "exchange_converters.go"
+	case MainFile:
+		return "main.go"
+	case ProtoFile:
+		return util.ToSnakeCase(info.Iface.Name) + ".proto"
+	case ProtoLockFile:
+		return util.ToSnakeCase(info.Iface.Name) + ".proto.lock"
+	case OpenAPIFile:
+		return openapiFileName(info)
+	case ErrorsFile, GRPCErrorsFile, HTTPErrorsFile:
+		return "errors.go"
+	case MockFile:
+		return "mock.go"
+	case HTTPTestFile, GRPCTestFile:
+		return "transport_test.go"
+	}
+	panic(fmt.Sprintf("unknown file kind %d", kind))
+}
+
+func (legacyLayout) EndpointsStructName(info *GenerationInfo) string {
+	if info.Namespace == "" {
+		return "Endpoints"
+	}
+	return info.Iface.Name + "Endpoints"
+}
+
+// Prefixes file name with namespace, when package contains several services.
+//
+//		endpoints.go or string_service_endpoints.go
+//
+func namespaced(info *GenerationInfo, name string) string {
+	if info.Namespace == "" {
+		return name
+	}
+	return info.Namespace + "_" + name
+}
+
+// Layout of go-kit addsvc example. Every service has its own packages,
+// named after service, so namespace is not needed.
+//
+//		./pkg/stringendpoint/set.go
+//		./pkg/stringendpoint/exchanges.go
+//		./pkg/stringservice/middleware.go
+//		./pkg/stringtransport/grpc_server.go
+//		./pkg/stringtransport/http_client.go
+//		./pkg/stringtransport/grpcconv/endpoint_converters.go
+//		./pkg/stringtransport/httpconv/exchange_converters.go
+//		./cmd/stringsvc/main.go
+//		./pb/stringsvc.proto
+//
+// Service interface is expected in ./pkg/stringservice, but it is not required.
+type addsvcLayout struct{}
+
+func (addsvcLayout) Name() string {
+	return AddsvcLayoutName
+}
+
+func (addsvcLayout) Package(info *GenerationInfo, kind PackageKind) (string, string) {
+	prefix := servicePrefix(info)
+	switch kind {
+	case EndpointPackage:
+		return filepath.Join("pkg", prefix+"endpoint"), prefix + "endpoint"
+	case MiddlewarePackage:
+		return filepath.Join("pkg", prefix+"service"), prefix + "service"
+	case GRPCTransportPackage, HTTPTransportPackage:
+		return filepath.Join("pkg", prefix+"transport"), prefix + "transport"
+	case GRPCConverterPackage:
+		return filepath.Join("pkg", prefix+"transport", "grpcconv"), "grpcconv"
+	case HTTPConverterPackage:
+		return filepath.Join("pkg", prefix+"transport", "httpconv"), "httpconv"
+	case MainPackage:
+		return filepath.Join("cmd", prefix+"svc"), "main"
+	case ProtoPackage:
+		return "pb", protoPackageName(info)
+	case ErrorPackage:
+		return filepath.Join("pkg", prefix+"error"), prefix + "error"
+	case MockPackage:
+		return filepath.Join("pkg", prefix+"mock"), prefix + "mock"
+	}
+	panic(fmt.Sprintf("unknown package kind %d", kind))
+}
+
+func (addsvcLayout) FileName(info *GenerationInfo, kind FileKind) string {
+	switch kind {
+	case EndpointsFile:
+		return "set.go"
+	case ExchangesFile:
+		return "exchanges.go"
+	case MiddlewareFile:
+		return "middleware.go"
+	case LoggingFile:
+		return "logging.go"
+	case RecoveringFile:
+		return "recovering.go"
+	case MetricsFile:
+		return "metrics.go"
+	case TracingFile:
+		return "tracing.go"
+	case GRPCServerFile:
+		return "grpc_server.go"
+	case GRPCClientFile:
+		return "grpc_client.go"
+	case HTTPServerFile:
+		return "http_server.go"
+	case HTTPClientFile:
+		return "http_client.go"
+	case GRPCEndpointConvertersFile:
+		return "endpoint_converters.go"
+	case GRPCTypeConvertersFile:
+		return "type_converters.go"
+	case HTTPConvertersFile:
+		return "exchange_converters.go"
+	case MainFile:
+		return "main.go"
+	case ProtoFile:
+		return servicePrefix(info) + "svc.proto"
+	case ProtoLockFile:
+		return servicePrefix(info) + "svc.proto.lock"
+	case OpenAPIFile:
+		return openapiFileName(info)
+	case ErrorsFile, HTTPErrorsFile:
+		return "errors.go"
+	case GRPCErrorsFile:
+		return "grpc_errors.go"
+	case MockFile:
+		return "mock.go"
+	case HTTPTestFile:
+		return "http_test.go"
+	case GRPCTestFile:
+		return "grpc_test.go"
+	}
+	panic(fmt.Sprintf("unknown file kind %d", kind))
+}
+
+func (addsvcLayout) EndpointsStructName(*GenerationInfo) string {
+	return "Endpoints"
+}
+
+// Returns lower case name of service without `Service` suffix.
+//
+//		StringService -> string
+//
+func servicePrefix(info *GenerationInfo) string {
+	name := strings.TrimSuffix(info.Iface.Name, "Service")
+	if name == "" {
+		name = info.Iface.Name
+	}
+	return strings.ToLower(name)
+}
+
+func (info *GenerationInfo) layout() Layout {
+	if info.Layout == nil {
+		return legacyLayout{}
+	}
+	return info.Layout
+}
+
+// Returns relative to output directory path of file.
+//
+//		./transport/grpc/server.go
+//
+func (info *GenerationInfo) filePath(kind FileKind) string {
+	dir, _ := info.layout().Package(info, filePackages[kind])
+	return "./" + filepath.Join(dir, info.layout().FileName(info, kind))
+}
+
+// Returns import path of package.
+func (info *GenerationInfo) importPath(kind PackageKind) string {
+	dir, _ := info.layout().Package(info, kind)
+	return path.Join(info.OutImportPath, filepath.ToSlash(dir))
+}
+
+// Creates new file for package, references to this package are rendered without qualifier.
+// Name of package may be overridden by configuration, except package in output directory,
+// which name is the name of service package.
+func (info *GenerationInfo) newFile(kind PackageKind) *File {
+	dir, name := info.layout().Package(info, kind)
+	if custom, ok := info.PackageNames[kind]; ok && dir != "" {
+		name = custom
+	}
+	return NewFilePathName(info.importPath(kind), name)
+}
+
+// Name of structure with all service endpoints.
+func endpointsStructName(info *GenerationInfo) string {
+	return info.layout().EndpointsStructName(info)
+}
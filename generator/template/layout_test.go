@@ -0,0 +1,95 @@
+package template
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestLayoutPaths(t *testing.T) {
+	iface := &types.Interface{Name: "StringService"}
+	tests := []struct {
+		layout     string
+		namespace  string
+		file       FileKind
+		path       string
+		pkg        PackageKind
+		importPath string
+	}{
+		{LegacyLayoutName, "", EndpointsFile, "./endpoints.go", EndpointPackage, "github.com/user/svc"},
+		{LegacyLayoutName, "string_service", EndpointsFile, "./string_service_endpoints.go", EndpointPackage, "github.com/user/svc"},
+		{LegacyLayoutName, "", GRPCServerFile, "./transport/grpc/server.go", GRPCConverterPackage, "github.com/user/svc/transport/converter/protobuf"},
+		{LegacyLayoutName, "string_service", LoggingFile, "./string_service/middleware/logging.go", MiddlewarePackage, "github.com/user/svc/string_service/middleware"},
+		{LegacyLayoutName, "", MainFile, "./cmd/string_service/main.go", HTTPConverterPackage, "github.com/user/svc/transport/converter/http"},
+		{AddsvcLayoutName, "", EndpointsFile, "./pkg/stringendpoint/set.go", EndpointPackage, "github.com/user/svc/pkg/stringendpoint"},
+		{AddsvcLayoutName, "string_service", HTTPClientFile, "./pkg/stringtransport/http_client.go", GRPCTransportPackage, "github.com/user/svc/pkg/stringtransport"},
+		{AddsvcLayoutName, "", MainFile, "./cmd/stringsvc/main.go", MiddlewarePackage, "github.com/user/svc/pkg/stringservice"},
+		{AddsvcLayoutName, "", MockFile, "./pkg/stringmock/mock.go", MockPackage, "github.com/user/svc/pkg/stringmock"},
+	}
+	for i, test := range tests {
+		layout, err := LayoutByName(test.layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := &GenerationInfo{
+			Iface:                    iface,
+			ServiceImportPackageName: "svc",
+			OutImportPath:            "github.com/user/svc",
+			Namespace:                test.namespace,
+			Layout:                   layout,
+		}
+		if path := info.filePath(test.file); path != test.path {
+			t.Errorf("%d: %s != %s", i+1, path, test.path)
+		}
+		if importPath := info.importPath(test.pkg); importPath != test.importPath {
+			t.Errorf("%d: %s != %s", i+1, importPath, test.importPath)
+		}
+	}
+}
+
+func TestLayoutByNameUnknown(t *testing.T) {
+	if _, err := LayoutByName("flat"); err == nil {
+		t.Error("expected error for unknown layout")
+	}
+}
+
+func TestAddsvcLayoutQualifiesServiceTypes(t *testing.T) {
+	ctx := types.Variable{Base: types.Base{Name: "ctx"}, Type: types.TImport{Import: &types.Import{Package: PackagePathContext}, Next: types.TName{TypeName: "Context"}}}
+	comment := types.Variable{Base: types.Base{Name: "comment"}, Type: types.TPointer{NumberOfPointers: 1, Next: types.TName{TypeName: "Comment"}}}
+	errVar := types.Variable{Base: types.Base{Name: "err"}, Type: types.TName{TypeName: "error"}}
+	layout, err := LayoutByName(AddsvcLayoutName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &GenerationInfo{
+		Iface: &types.Interface{
+			Base: types.Base{Name: "StringService"},
+			Methods: []*types.Function{
+				{Base: types.Base{Name: "Count"}, Args: []types.Variable{ctx, comment}, Results: []types.Variable{errVar}},
+			},
+		},
+		ServiceImportPackageName: "stringsvc",
+		ServiceImportPath:        "github.com/user/stringsvc",
+		OutImportPath:            "github.com/user/stringsvc",
+		AbsOutPath:               "/tmp/out",
+		SourceFilePath:           "/tmp/out/service.go",
+		Layout:                   layout,
+	}
+	for _, tmpl := range []Template{
+		NewExchangeTemplate(info),
+		NewEndpointsTemplate(info),
+		NewLoggingTemplate(info),
+		NewRecoverTemplate(info),
+	} {
+		code := renderTemplate(t, tmpl)
+		if !strings.Contains(code, "*stringsvc.Comment") {
+			t.Errorf("%s: type of service package is not qualified:\n%s", tmpl.DefaultPath(), code)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), tmpl.DefaultPath(), code, 0); err != nil {
+			t.Errorf("%s: %v", tmpl.DefaultPath(), err)
+		}
+	}
+}
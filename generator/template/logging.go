@@ -24,7 +24,7 @@ type loggingTemplate struct {
 
 func NewLoggingTemplate(info *GenerationInfo) Template {
 	return &loggingTemplate{
-		Info: info,
+		Info: info.qualified(),
 	}
 }
 
@@ -69,7 +69,7 @@ func NewLoggingTemplate(info *GenerationInfo) Template {
 //		}
 //
 func (t *loggingTemplate) Render() write_strategy.Renderer {
-	f := NewFile("middleware")
+	f := t.Info.newFile(MiddlewarePackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
@@ -94,8 +94,8 @@ func (t *loggingTemplate) Render() write_strategy.Renderer {
 	return f
 }
 
-func (loggingTemplate) DefaultPath() string {
-	return "./middleware/logging.go"
+func (t *loggingTemplate) DefaultPath() string {
+	return t.Info.filePath(LoggingFile)
 }
 
 func (t *loggingTemplate) Prepare() error {
@@ -1,8 +1,6 @@
 package template
 
 import (
-	"path/filepath"
-
 	. "github.com/dave/jennifer/jen"
 	"github.com/devimteam/microgen/generator/write_strategy"
 	"github.com/devimteam/microgen/util"
@@ -13,6 +11,8 @@ type mainTemplate struct {
 
 	logging    bool
 	recovering bool
+	metrics    bool
+	tracing    bool
 	grpcServer bool
 	httpServer bool
 }
@@ -24,7 +24,7 @@ func NewMainTemplate(info *GenerationInfo) Template {
 }
 
 func (t *mainTemplate) Render() write_strategy.Renderer {
-	f := NewFile("main")
+	f := t.Info.newFile(MainPackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`This file will never be overwritten.`)
 
@@ -33,12 +33,13 @@ func (t *mainTemplate) Render() write_strategy.Renderer {
 	f.Line().Add(t.interruptHandler())
 	f.Line().Add(t.serveGrpc())
 	f.Line().Add(t.serveHTTP())
+	f.Line().Add(t.serveMetrics())
 
 	return f
 }
 
 func (t *mainTemplate) DefaultPath() string {
-	return "./cmd/" + util.ToSnakeCase(t.Info.Iface.Name) + "/main.go"
+	return t.Info.filePath(MainFile)
 }
 
 func (t *mainTemplate) Prepare() error {
@@ -49,6 +50,10 @@ func (t *mainTemplate) Prepare() error {
 			t.recovering = true
 		case LoggingMiddlewareTag:
 			t.logging = true
+		case MetricsMiddlewareTag:
+			t.metrics = true
+		case TracingMiddlewareTag:
+			t.tracing = true
 		case HttpServerTag, HttpTag:
 			t.httpServer = true
 		case GrpcTag, GrpcServerTag:
@@ -92,23 +97,28 @@ func (t *mainTemplate) mainFunc() *Statement {
 			Comment(`Create new service.`)
 		if t.logging {
 			main.Id("service").Op("=").
-				Qual(filepath.Join(t.Info.ServiceImportPath, "middleware"), "ServiceLogging").Call(Id("logger")).Call(Id("service")).
+				Qual(t.Info.importPath(MiddlewarePackage), "ServiceLogging").Call(Id("logger")).Call(Id("service")).
 				Comment(`Setup service logging.`)
 		}
 		if t.recovering {
 			main.Id("service").Op("=").
-				Qual(filepath.Join(t.Info.ServiceImportPath, "middleware"), "ServiceRecovering").Call(Id("logger")).Call(Id("service")).
+				Qual(t.Info.importPath(MiddlewarePackage), "ServiceRecovering").Call(Id("logger")).Call(Id("service")).
 				Comment(`Setup service recovering.`)
 		}
+		if t.tracing {
+			main.Id("service").Op("=").
+				Qual(t.Info.importPath(MiddlewarePackage), "ServiceTracing").Call(Qual(PackagePathOpenTracing, "GlobalTracer").Call()).Call(Id("service")).
+				Comment(`Setup service tracing.`)
+		}
+		if t.metrics {
+			main.Add(t.serviceMetrics())
+		}
 		main.Line()
-		main.Id("endpoints").Op(":=").Op("&").Qual(t.Info.ServiceImportPath, "Endpoints").Values(DictFunc(func(p Dict) {
-			for _, method := range t.Info.Iface.Methods {
-				p[Id(endpointStructName(method.Name))] = Qual(t.Info.ServiceImportPath, endpointStructName(method.Name)).Call(Id("service"))
-			}
-		}))
+		main.Comment(`Here you can add global and per method endpoint middlewares.`)
+		main.Id("endpoints").Op(":=").Qual(t.Info.importPath(EndpointPackage), endpointsConstructorName(t.Info)).Call(Id("service"), Nil(), Nil())
 		if t.grpcServer {
 			main.Line()
-			main.Id("grpcAddr").Op(":=").Lit(":8081")
+			main.Id("grpcAddr").Op(":=").Lit(t.Info.GRPCAddr)
 			main.Comment(`Start grpc server.`)
 			main.Go().Id("ServeGRPC").Call(
 				Id("endpoints"),
@@ -119,7 +129,7 @@ func (t *mainTemplate) mainFunc() *Statement {
 		}
 		if t.httpServer {
 			main.Line()
-			main.Id("httpAddr").Op(":=").Lit(":8080")
+			main.Id("httpAddr").Op(":=").Lit(t.Info.HTTPAddr)
 			main.Comment(`Start http server.`)
 			main.Go().Id("ServeHTTP").Call(
 				Id("endpoints"),
@@ -128,11 +138,65 @@ func (t *mainTemplate) mainFunc() *Statement {
 				Qual(PackagePathGoKitLog, "With").Call(Id("logger"), Lit("transport"), Lit("HTTP")),
 			)
 		}
+		if t.metrics {
+			main.Line()
+			main.Id("metricsAddr").Op(":=").Lit(t.Info.MetricsAddr)
+			main.Comment(`Expose metrics for prometheus.`)
+			main.Go().Id("ServeMetrics").Call(
+				Id("errorChan"),
+				Id("metricsAddr"),
+				Qual(PackagePathGoKitLog, "With").Call(Id("logger"), Lit("transport"), Lit("metrics")),
+			)
+		}
 		main.Line()
 		main.Id("logger").Dot("Log").Call(Lit("error"), Op("<-").Id("errorChan"))
 	})
 }
 
+// Wraps service with metrics middleware, which uses prometheus metrics from default registry.
+// Metrics are exposed by ServeMetrics.
+//
+//		service = middleware.ServiceMetrics(
+//			kitprometheus.NewCounterFrom(prometheus.CounterOpts{
+//				Help:      "Number of requests received.",
+//				Name:      "request_count",
+//				Namespace: "string_service",
+//			}, []string{"method"}),
+//			...
+//		)(service) // Setup service metrics.
+//
+func (t *mainTemplate) serviceMetrics() *Statement {
+	namespace := util.ToSnakeCase(t.Info.Iface.Name)
+	opts := func(name, help string) Dict {
+		return Dict{
+			Id("Namespace"): Lit(namespace),
+			Id("Name"):      Lit(name),
+			Id("Help"):      Lit(help),
+		}
+	}
+	labels := Index().String().Values(Lit("method"))
+	return Id("service").Op("=").Qual(t.Info.importPath(MiddlewarePackage), "ServiceMetrics").Call(
+		Line().Qual(PackagePathGoKitPrometheus, "NewCounterFrom").Call(
+			Qual(PackagePathPrometheus, "CounterOpts").Values(opts("request_count", "Number of requests received.")), labels),
+		Line().Qual(PackagePathGoKitPrometheus, "NewCounterFrom").Call(
+			Qual(PackagePathPrometheus, "CounterOpts").Values(opts("error_count", "Number of requests failed with error.")), labels),
+		Line().Qual(PackagePathGoKitPrometheus, "NewHistogramFrom").Call(
+			Qual(PackagePathPrometheus, "HistogramOpts").Values(opts("request_latency_seconds", "Duration of requests in seconds.")), labels),
+		Line(),
+	).Call(Id("service")).Comment(`Setup service metrics.`)
+}
+
+// Renders arguments of transport constructors for tracing, which uses global tracer.
+//
+//		opentracing.GlobalTracer(), logger
+//
+func (t *mainTemplate) tracingArgs() *Statement {
+	if !t.tracing {
+		return nil
+	}
+	return List(Qual(PackagePathOpenTracing, "GlobalTracer").Call(), Id("logger"))
+}
+
 // Renders something like this
 //		func initLogger() {
 //			logger = log.NewJSONLogger(os.Stdout)
@@ -178,7 +242,7 @@ func (t *mainTemplate) serveGrpc() *Statement {
 	}
 	return Comment(`ServeGRPC starts new GRPC server on address and sends first error to channel.`).Line().
 		Func().Id("ServeGRPC").Params(
-		Id("endpoints").Op("*").Qual(t.Info.ServiceImportPath, "Endpoints"),
+		Id("endpoints").Op("*").Qual(t.Info.importPath(EndpointPackage), endpointsStructName(t.Info)),
 		Id("ch").Id("chan<- error"),
 		Id("addr").Id("string"),
 		Id("logger").Qual(PackagePathGoKitLog, "Logger"),
@@ -189,7 +253,7 @@ func (t *mainTemplate) serveGrpc() *Statement {
 			Return(),
 		)
 		body.Comment(`Here you can add middlewares for grpc server.`)
-		body.Id("server").Op(":=").Qual(filepath.Join(t.Info.ServiceImportPath, "transport/grpc"), "NewGRPCServer").Call(Id("endpoints"))
+		body.Id("server").Op(":=").Qual(t.Info.importPath(GRPCTransportPackage), "NewGRPCServer").Call(Id("endpoints"), t.tracingArgs())
 		body.Id("grpcServer").Op(":=").Qual(PackagePathGoogleGRPC, "NewServer").Call()
 		body.Qual(t.Info.ProtobufPackage, "Register"+util.ToUpperFirst(t.Info.Iface.Name)+"Server").Call(Id("grpcServer"), Id("server"))
 		body.Id("logger").Dot("Log").Call(Lit("listen on"), Id("addr"))
@@ -203,12 +267,12 @@ func (t *mainTemplate) serveHTTP() *Statement {
 	}
 	return Comment(`ServeHTTP starts new HTTP server on address and sends first error to channel.`).Line().
 		Func().Id("ServeHTTP").Params(
-		Id("endpoints").Op("*").Qual(t.Info.ServiceImportPath, "Endpoints"),
+		Id("endpoints").Op("*").Qual(t.Info.importPath(EndpointPackage), endpointsStructName(t.Info)),
 		Id("ch").Id("chan<- error"),
 		Id("addr").Id("string"),
 		Id("logger").Qual(PackagePathGoKitLog, "Logger"),
 	).BlockFunc(func(body *Group) {
-		body.Id("handler").Op(":=").Qual(t.Info.ServiceImportPath+"/transport/http", "NewHTTPHandler").Call(Id("endpoints"))
+		body.Id("handler").Op(":=").Qual(t.Info.importPath(HTTPTransportPackage), "NewHTTPHandler").Call(Id("endpoints"), t.tracingArgs())
 		body.Id("httpServer").Op(":=").Op("&").Qual(PackagePathHttp, "Server").Values(DictFunc(func(d Dict) {
 			d[Id("Addr")] = Id("addr")
 			d[Id("Handler")] = Id("handler")
@@ -217,3 +281,29 @@ func (t *mainTemplate) serveHTTP() *Statement {
 		body.Id("ch").Op("<-").Id("httpServer").Dot("ListenAndServe").Call()
 	})
 }
+
+// Renders server, which exposes metrics from default registry on /metrics path.
+//
+//		func ServeMetrics(ch chan<- error, addr string, logger log.Logger) {
+//			mux := http.NewServeMux()
+//			mux.Handle("/metrics", promhttp.Handler())
+//			logger.Log("listen on", addr)
+//			ch <- http.ListenAndServe(addr, mux)
+//		}
+//
+func (t *mainTemplate) serveMetrics() *Statement {
+	if !t.metrics {
+		return nil
+	}
+	return Comment(`ServeMetrics starts new HTTP server, which exposes prometheus metrics on /metrics path, and sends first error to channel.`).Line().
+		Func().Id("ServeMetrics").Params(
+		Id("ch").Id("chan<- error"),
+		Id("addr").Id("string"),
+		Id("logger").Qual(PackagePathGoKitLog, "Logger"),
+	).BlockFunc(func(body *Group) {
+		body.Id("mux").Op(":=").Qual(PackagePathHttp, "NewServeMux").Call()
+		body.Id("mux").Dot("Handle").Call(Lit("/metrics"), Qual(PackagePathPrometheusHTTP, "Handler").Call())
+		body.Id("logger").Dot("Log").Call(Lit("listen on"), Id("addr"))
+		body.Id("ch").Op("<-").Qual(PackagePathHttp, "ListenAndServe").Call(Id("addr"), Id("mux"))
+	})
+}
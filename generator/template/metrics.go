@@ -0,0 +1,164 @@
+package template
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+const (
+	serviceMetricsStructName = "serviceMetrics"
+	requestCountVarName      = "requestCount"
+	errorCountVarName        = "errorCount"
+	requestLatencyVarName    = "requestLatency"
+)
+
+type metricsTemplate struct {
+	Info *GenerationInfo
+}
+
+func NewMetricsTemplate(info *GenerationInfo) Template {
+	return &metricsTemplate{
+		Info: info.qualified(),
+	}
+}
+
+// Render all metrics.go file.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//		package middleware
+//
+//		import (
+//			context "context"
+//			svc "github.com/devimteam/microgen/example/svc"
+//			metrics "github.com/go-kit/kit/metrics"
+//			time "time"
+//		)
+//
+//		func ServiceMetrics(requestCount metrics.Counter, errorCount metrics.Counter, requestLatency metrics.Histogram) Middleware {
+//			return func(next svc.StringService) svc.StringService {
+//				return &serviceMetrics{
+//					errorCount:     errorCount,
+//					next:           next,
+//					requestCount:   requestCount,
+//					requestLatency: requestLatency,
+//				}
+//			}
+//		}
+//
+//		type serviceMetrics struct {
+//			requestCount   metrics.Counter
+//			errorCount     metrics.Counter
+//			requestLatency metrics.Histogram
+//			next           svc.StringService
+//		}
+//
+//		func (s *serviceMetrics) Count(ctx context.Context, text string, symbol string) (count int, err error) {
+//			defer func(begin time.Time) {
+//				s.requestCount.With("method", "Count").Add(1)
+//				if err != nil {
+//					s.errorCount.With("method", "Count").Add(1)
+//				}
+//				s.requestLatency.With("method", "Count").Observe(time.Since(begin).Seconds())
+//			}(time.Now())
+//			return s.next.Count(ctx, text, symbol)
+//		}
+//
+func (t *metricsTemplate) Render() write_strategy.Renderer {
+	f := t.Info.newFile(MiddlewarePackage)
+	f.PackageComment(FileHeader)
+	f.PackageComment(`Please, do not edit.`)
+
+	f.Comment("ServiceMetrics counts requests and errors and observes latency in seconds of method calls, labelled by method.").
+		Line().Func().Id(util.ToUpperFirst(serviceMetricsStructName)).Params(
+		Id(requestCountVarName).Qual(PackagePathGoKitMetrics, "Counter"),
+		Id(errorCountVarName).Qual(PackagePathGoKitMetrics, "Counter"),
+		Id(requestLatencyVarName).Qual(PackagePathGoKitMetrics, "Histogram"),
+	).Params(Id(MiddlewareTypeName)).
+		Block(t.newMetricsBody(t.Info.Iface))
+
+	f.Line()
+
+	f.Type().Id(serviceMetricsStructName).Struct(
+		Id(requestCountVarName).Qual(PackagePathGoKitMetrics, "Counter"),
+		Id(errorCountVarName).Qual(PackagePathGoKitMetrics, "Counter"),
+		Id(requestLatencyVarName).Qual(PackagePathGoKitMetrics, "Histogram"),
+		Id(nextVarName).Qual(t.Info.ServiceImportPath, t.Info.Iface.Name),
+	)
+
+	for _, signature := range t.Info.Iface.Methods {
+		f.Line()
+		f.Add(t.metricsFunc(signature)).Line()
+	}
+
+	return f
+}
+
+func (t *metricsTemplate) DefaultPath() string {
+	return t.Info.filePath(MetricsFile)
+}
+
+func (t *metricsTemplate) Prepare() error {
+	return nil
+}
+
+func (t *metricsTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
+
+func (t *metricsTemplate) newMetricsBody(i *types.Interface) *Statement {
+	return Return(Func().Params(
+		Id(nextVarName).Qual(t.Info.ServiceImportPath, i.Name),
+	).Params(
+		Qual(t.Info.ServiceImportPath, i.Name),
+	).BlockFunc(func(g *Group) {
+		g.Return(Op("&").Id(serviceMetricsStructName).Values(
+			Dict{
+				Id(requestCountVarName):   Id(requestCountVarName),
+				Id(errorCountVarName):     Id(errorCountVarName),
+				Id(requestLatencyVarName): Id(requestLatencyVarName),
+				Id(nextVarName):           Id(nextVarName),
+			},
+		))
+	}))
+}
+
+func (t *metricsTemplate) metricsFunc(signature *types.Function) *Statement {
+	return methodDefinition(serviceMetricsStructName, signature).
+		BlockFunc(t.metricsFuncBody(signature))
+}
+
+// Render metrics function body. Errors are counted only for methods, which return error last.
+//
+//		defer func(begin time.Time) {
+//			s.requestCount.With("method", "Count").Add(1)
+//			if err != nil {
+//				s.errorCount.With("method", "Count").Add(1)
+//			}
+//			s.requestLatency.With("method", "Count").Observe(time.Since(begin).Seconds())
+//		}(time.Now())
+//		return s.next.Count(ctx, text, symbol)
+//
+func (t *metricsTemplate) metricsFuncBody(signature *types.Function) func(g *Group) {
+	receiver := util.LastUpperOrFirst(serviceMetricsStructName)
+	with := func(metric string) *Statement {
+		return Id(receiver).Dot(metric).Dot("With").Call(Lit("method"), Lit(signature.Name))
+	}
+	return func(g *Group) {
+		g.Defer().Func().Params(Id("begin").Qual(PackagePathTime, "Time")).BlockFunc(func(body *Group) {
+			body.Add(with(requestCountVarName).Dot("Add").Call(Lit(1)))
+			if IsErrorLast(signature.Results) {
+				body.If(Id(nameOfLastResultError(signature)).Op("!=").Nil()).Block(
+					with(errorCountVarName).Dot("Add").Call(Lit(1)),
+				)
+			}
+			body.Add(with(requestLatencyVarName).Dot("Observe").Call(
+				Qual(PackagePathTime, "Since").Call(Id("begin")).Dot("Seconds").Call(),
+			))
+		}).Call(Qual(PackagePathTime, "Now").Call())
+
+		g.Return().Id(receiver).Dot(nextVarName).Dot(signature.Name).Call(paramNames(signature.Args))
+	}
+}
@@ -0,0 +1,25 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	text := types.Variable{Base: types.Base{Name: "text"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, "/tmp/out", text)
+	info.Iface.Docs = []string{"// @microgen metrics, main"}
+
+	testRender(t, []renderCase{
+		{"middleware", NewMetricsTemplate(info), []string{
+			".requestCount.With(\"method\", \"Count\").Add(1)",
+			"M.errorCount.With(\"method\", \"Count\").Add(1)",
+			".requestLatency.With(\"method\", \"Count\").Observe(time.Since(begin).Seconds())",
+		}},
+		{"main", NewMainTemplate(info), []string{
+			"service = middleware.ServiceMetrics(",
+			"mux.Handle(\"/metrics\", promhttp.Handler())",
+		}},
+	})
+}
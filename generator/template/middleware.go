@@ -15,7 +15,7 @@ type middlewareTemplate struct {
 
 func NewMiddlewareTemplate(info *GenerationInfo) Template {
 	return &middlewareTemplate{
-		Info: info,
+		Info: info.qualified(),
 	}
 }
 
@@ -30,7 +30,7 @@ func NewMiddlewareTemplate(info *GenerationInfo) Template {
 //		type Middleware func(svc.StringService) svc.StringService
 //
 func (t *middlewareTemplate) Render() write_strategy.Renderer {
-	f := NewFile("middleware")
+	f := t.Info.newFile(MiddlewarePackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 	f.Comment("Service middleware").
@@ -38,8 +38,8 @@ func (t *middlewareTemplate) Render() write_strategy.Renderer {
 	return f
 }
 
-func (middlewareTemplate) DefaultPath() string {
-	return "./middleware/middleware.go"
+func (t *middlewareTemplate) DefaultPath() string {
+	return t.Info.filePath(MiddlewareFile)
 }
 
 func (middlewareTemplate) Prepare() error {
@@ -0,0 +1,288 @@
+package template
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+const (
+	mockMutexName        = "mx"
+	mockExpectationsName = "expectations"
+	mockTestingTName     = "TestingT"
+)
+
+type mockTemplate struct {
+	Info *GenerationInfo
+}
+
+func NewMockTemplate(info *GenerationInfo) Template {
+	return &mockTemplate{
+		Info: info,
+	}
+}
+
+// Render whole mock.go file.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//		package stringmock
+//
+//		// StringService is a mock of svc.StringService, which is safe for concurrent use.
+//		type StringService struct {
+//			CountFunc func(ctx context.Context, text string, symbol string) (count int, err error)
+//
+//			mx           sync.Mutex
+//			countCalls   []CountCall
+//			expectations map[string]int
+//		}
+//
+//		// CountCall holds arguments of Count call.
+//		type CountCall struct {
+//			Ctx    context.Context
+//			Text   string
+//			Symbol string
+//		}
+//
+//		func (S *StringService) Count(ctx context.Context, text string, symbol string) (count int, err error) {
+//			...
+//		}
+//
+func (t *mockTemplate) Render() write_strategy.Renderer {
+	f := t.Info.newFile(MockPackage)
+	f.PackageComment(FileHeader)
+	f.PackageComment(`Please, do not edit.`)
+
+	iface := t.Info.Iface.Name
+	f.Comment(iface + " is a mock of " + t.Info.ServiceImportPackageName + "." + iface + ", which is safe for concurrent use.").
+		Line().Comment("Methods call functions from fields with `Func` suffix, methods without functions return zero values.").
+		Line().Comment("Set functions before the mock is used.").
+		Line().Type().Id(iface).StructFunc(func(g *Group) {
+		for _, signature := range t.signatures() {
+			g.Id(mockFuncName(signature)).Func().Params(funcDefinitionParams(signature.Args)).Params(funcDefinitionParams(signature.Results))
+		}
+		g.Line()
+		g.Id(mockMutexName).Qual(PackagePathSync, "Mutex")
+		for _, signature := range t.signatures() {
+			g.Id(mockCallsName(signature)).Index().Id(mockCallName(signature))
+		}
+		// Expected numbers of calls by method names.
+		g.Id(mockExpectationsName).Map(String()).Int()
+	})
+	f.Line()
+	f.Var().Id("_").Qual(t.Info.ServiceImportPath, iface).Op("=").Op("&").Id(iface).Values()
+
+	f.Line()
+	f.Comment(mockTestingTName + " is a part of testing.TB, which is used to report failed expectations.").
+		Line().Type().Id(mockTestingTName).Interface(
+		Id("Errorf").Params(Id("format").String(), Id("args").Op("...").Interface()),
+	)
+
+	for _, signature := range t.signatures() {
+		f.Line()
+		f.Add(t.callType(signature)).Line()
+		f.Add(t.mockFunc(signature)).Line()
+		f.Add(t.callsFunc(signature)).Line()
+		f.Add(t.callCountFunc(signature)).Line()
+		f.Add(t.expectFunc(signature))
+	}
+	f.Line()
+	f.Add(t.assertFunc())
+
+	return f
+}
+
+func (t *mockTemplate) DefaultPath() string {
+	return t.Info.filePath(MockFile)
+}
+
+func (t *mockTemplate) Prepare() error {
+	return nil
+}
+
+func (t *mockTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
+
+// Returns methods of interface, which types, declared in service package, are qualified,
+// because mock is placed in its own package.
+func (t *mockTemplate) signatures() []*types.Function {
+	return qualifySignatures(t.Info.Iface.Methods, t.Info.serviceImport())
+}
+
+// Render structure with arguments of call. Variadic arguments are stored as slices.
+//
+//		// UppercaseCall holds arguments of Uppercase call.
+//		type UppercaseCall struct {
+//			Ctx context.Context
+//			Str []map[string]interface{}
+//		}
+//
+func (t *mockTemplate) callType(signature *types.Function) *Statement {
+	return Comment(mockCallName(signature)+" holds arguments of "+signature.Name+" call.").
+		Line().Type().Id(mockCallName(signature)).StructFunc(func(g *Group) {
+		for _, arg := range signature.Args {
+			g.Add(structFieldName(&arg)).Add(fieldType(arg.Type, false))
+		}
+	})
+}
+
+// Render method, which records call and calls function of mock.
+//
+//		func (S *StringService) Uppercase(ctx context.Context, str ...map[string]interface{}) (ans string, err error) {
+//			S.mx.Lock()
+//			S.uppercaseCalls = append(S.uppercaseCalls, UppercaseCall{
+//				Ctx: ctx,
+//				Str: str,
+//			})
+//			fn := S.UppercaseFunc // fn1, when method has argument fn
+//			S.mx.Unlock()
+//			if fn == nil {
+//				return
+//			}
+//			return fn(ctx, str...)
+//		}
+//
+func (t *mockTemplate) mockFunc(signature *types.Function) *Statement {
+	receiver := util.LastUpperOrFirst(t.Info.Iface.Name)
+	return methodDefinition(t.Info.Iface.Name, signature).BlockFunc(func(g *Group) {
+		g.Id(receiver).Dot(mockMutexName).Dot("Lock").Call()
+		g.Id(receiver).Dot(mockCallsName(signature)).Op("=").Append(
+			Id(receiver).Dot(mockCallsName(signature)),
+			Id(mockCallName(signature)).Values(dictByVariables(signature.Args)),
+		)
+		fn := localName("fn", signature)
+		g.Id(fn).Op(":=").Id(receiver).Dot(mockFuncName(signature))
+		g.Id(receiver).Dot(mockMutexName).Dot("Unlock").Call()
+		g.If(Id(fn).Op("==").Nil()).Block(Return())
+		g.Return(Id(fn).Call(paramNames(signature.Args)))
+	})
+}
+
+// Render getter of recorded calls.
+//
+//		// CountCalls returns arguments of all Count calls in order of calls.
+//		func (S *StringService) CountCalls() []CountCall {
+//			S.mx.Lock()
+//			defer S.mx.Unlock()
+//			return append([]CountCall(nil), S.countCalls...)
+//		}
+//
+func (t *mockTemplate) callsFunc(signature *types.Function) *Statement {
+	receiver := util.LastUpperOrFirst(t.Info.Iface.Name)
+	name := signature.Name + "Calls"
+	return Comment(name+" returns arguments of all "+signature.Name+" calls in order of calls.").
+		Line().Func().Params(Id(receiver).Op("*").Id(t.Info.Iface.Name)).Id(name).Params().Index().Id(mockCallName(signature)).Block(
+		Id(receiver).Dot(mockMutexName).Dot("Lock").Call(),
+		Defer().Id(receiver).Dot(mockMutexName).Dot("Unlock").Call(),
+		Return(Append(Index().Id(mockCallName(signature)).Call(Nil()), Id(receiver).Dot(mockCallsName(signature)).Op("..."))),
+	)
+}
+
+// Render counter of calls.
+//
+//		// CountCallCount returns number of Count calls.
+//		func (S *StringService) CountCallCount() int {
+//			S.mx.Lock()
+//			defer S.mx.Unlock()
+//			return len(S.countCalls)
+//		}
+//
+func (t *mockTemplate) callCountFunc(signature *types.Function) *Statement {
+	receiver := util.LastUpperOrFirst(t.Info.Iface.Name)
+	name := signature.Name + "CallCount"
+	return Comment(name+" returns number of "+signature.Name+" calls.").
+		Line().Func().Params(Id(receiver).Op("*").Id(t.Info.Iface.Name)).Id(name).Params().Int().Block(
+		Id(receiver).Dot(mockMutexName).Dot("Lock").Call(),
+		Defer().Id(receiver).Dot(mockMutexName).Dot("Unlock").Call(),
+		Return(Len(Id(receiver).Dot(mockCallsName(signature)))),
+	)
+}
+
+// Render setter of expected number of calls.
+//
+//		// ExpectCount sets expected number of Count calls, which is checked by AssertExpectations.
+//		func (S *StringService) ExpectCount(times int) {
+//			S.mx.Lock()
+//			defer S.mx.Unlock()
+//			if S.expectations == nil {
+//				S.expectations = make(map[string]int)
+//			}
+//			S.expectations["Count"] = times
+//		}
+//
+func (t *mockTemplate) expectFunc(signature *types.Function) *Statement {
+	receiver := util.LastUpperOrFirst(t.Info.Iface.Name)
+	name := "Expect" + signature.Name
+	return Comment(name+" sets expected number of "+signature.Name+" calls, which is checked by AssertExpectations.").
+		Line().Func().Params(Id(receiver).Op("*").Id(t.Info.Iface.Name)).Id(name).Params(Id("times").Int()).Block(
+		Id(receiver).Dot(mockMutexName).Dot("Lock").Call(),
+		Defer().Id(receiver).Dot(mockMutexName).Dot("Unlock").Call(),
+		If(Id(receiver).Dot(mockExpectationsName).Op("==").Nil()).Block(
+			Id(receiver).Dot(mockExpectationsName).Op("=").Make(Map(String()).Int()),
+		),
+		Id(receiver).Dot(mockExpectationsName).Index(Lit(signature.Name)).Op("=").Id("times"),
+	)
+}
+
+// Render check of all expectations.
+//
+//		// AssertExpectations reports every method, which was called not expected number of times, to t.
+//		// Returns true, when all expectations are met.
+//		func (S *StringService) AssertExpectations(t TestingT) bool {
+//			S.mx.Lock()
+//			defer S.mx.Unlock()
+//			ok := true
+//			if times, expected := S.expectations["Count"]; expected && len(S.countCalls) != times {
+//				t.Errorf("Count is called %d times, expected %d", len(S.countCalls), times)
+//				ok = false
+//			}
+//			return ok
+//		}
+//
+func (t *mockTemplate) assertFunc() *Statement {
+	receiver := util.LastUpperOrFirst(t.Info.Iface.Name)
+	return Comment("AssertExpectations reports every method, which was called not expected number of times, to t.").
+		Line().Comment("Returns true, when all expectations are met.").
+		Line().Func().Params(Id(receiver).Op("*").Id(t.Info.Iface.Name)).Id("AssertExpectations").Params(Id("t").Id(mockTestingTName)).Bool().BlockFunc(func(g *Group) {
+		g.Id(receiver).Dot(mockMutexName).Dot("Lock").Call()
+		g.Defer().Id(receiver).Dot(mockMutexName).Dot("Unlock").Call()
+		g.Id("ok").Op(":=").True()
+		for _, signature := range t.Info.Iface.Methods {
+			calls := Len(Id(receiver).Dot(mockCallsName(signature)))
+			g.If(
+				List(Id("times"), Id("expected")).Op(":=").Id(receiver).Dot(mockExpectationsName).Index(Lit(signature.Name)),
+				Id("expected").Op("&&").Add(calls).Op("!=").Id("times"),
+			).Block(
+				Id("t").Dot("Errorf").Call(Lit(signature.Name+" is called %d times, expected %d"), calls, Id("times")),
+				Id("ok").Op("=").False(),
+			)
+		}
+		g.Return(Id("ok"))
+	})
+}
+
+// Name of mock field with function of method.
+//
+//		CountFunc
+//
+func mockFuncName(signature *types.Function) string {
+	return signature.Name + "Func"
+}
+
+// Name of structure with arguments of method call.
+//
+//		CountCall
+//
+func mockCallName(signature *types.Function) string {
+	return signature.Name + "Call"
+}
+
+// Name of mock field with recorded calls of method.
+//
+//		countCalls
+//
+func mockCallsName(signature *types.Function) string {
+	return util.ToLowerFirst(signature.Name) + "Calls"
+}
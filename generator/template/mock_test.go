@@ -0,0 +1,48 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestMock(t *testing.T) {
+	text := types.Variable{Base: types.Base{Name: "text"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, "/tmp/out", text)
+	ctx := info.Iface.Methods[0].Args[0]
+	info.Iface.Methods = append(info.Iface.Methods, &types.Function{
+		Base: types.Base{Name: "Uppercase"},
+		Args: []types.Variable{ctx, {
+			Base: types.Base{Name: "str"},
+			Type: types.TEllipsis{Next: types.TMap{Key: types.TName{TypeName: "string"}, Value: types.TInterface{Interface: &types.Interface{}}}},
+		}},
+		Results: []types.Variable{
+			{Base: types.Base{Name: "ans"}, Type: types.TName{TypeName: "Answer"}},
+			{Base: types.Base{Name: "err"}, Type: types.TName{TypeName: "error"}},
+		},
+	}, &types.Function{
+		// Argument has the same name as local variable of mock.
+		Base: types.Base{Name: "Apply"},
+		Args: []types.Variable{ctx, {Base: types.Base{Name: "fn"}, Type: types.TName{TypeName: "string"}}},
+		Results: []types.Variable{
+			{Base: types.Base{Name: "err"}, Type: types.TName{TypeName: "error"}},
+		},
+	})
+
+	tmpl := NewMockTemplate(info)
+	if path := tmpl.DefaultPath(); path != "./stringmock/mock.go" {
+		t.Errorf("mock path is %s", path)
+	}
+	testRender(t, []renderCase{
+		{"mock", tmpl, []string{
+			"UppercaseFunc func(ctx context.Context, str ...map[string]interface{}) (ans stringsvc.Answer, err error)",
+			"var _ stringsvc.StringService = &StringService{}",
+			"S.uppercaseCalls = append(S.uppercaseCalls, UppercaseCall{",
+			"return fn(ctx, str...)",
+			// Local variable does not shadow argument.
+			"fn1 := S.ApplyFunc",
+			"return fn1(ctx, fn)",
+			"t.Errorf(\"Count is called %d times, expected %d\", len(S.countCalls), times)",
+		}},
+	})
+}
@@ -0,0 +1,417 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/vetcher/godecl/types"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OpenAPIFormatYAML = "yaml"
+	OpenAPIFormatJSON = "json"
+
+	openapiVersion      = "3.0.3"
+	openapiSchemaPrefix = "#/components/schemas/"
+	openapiContentJSON  = "application/json"
+)
+
+// Returns name of OpenAPI document with extension of its format.
+func openapiFileName(info *GenerationInfo) string {
+	if info.OpenAPIFormat == OpenAPIFormatJSON {
+		return "openapi.json"
+	}
+	return "openapi.yaml"
+}
+
+type openapiDocument struct {
+	OpenAPI    string                     `json:"openapi" yaml:"openapi"`
+	Info       openapiInfo                `json:"info" yaml:"info"`
+	Paths      map[string]openapiPathItem `json:"paths" yaml:"paths"`
+	Components openapiComponents          `json:"components" yaml:"components"`
+}
+
+type openapiInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+// Operations of path by lower case http method.
+type openapiPathItem map[string]*openapiOperation
+
+type openapiOperation struct {
+	OperationID string                      `json:"operationId" yaml:"operationId"`
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	Parameters  []*openapiParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *openapiRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*openapiResponse `json:"responses" yaml:"responses"`
+}
+
+type openapiParameter struct {
+	Name     string         `json:"name" yaml:"name"`
+	In       string         `json:"in" yaml:"in"`
+	Required bool           `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema   *openapiSchema `json:"schema" yaml:"schema"`
+}
+
+type openapiRequestBody struct {
+	Required bool                         `json:"required" yaml:"required"`
+	Content  map[string]*openapiMediaType `json:"content" yaml:"content"`
+}
+
+type openapiResponse struct {
+	Description string                       `json:"description" yaml:"description"`
+	Content     map[string]*openapiMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type openapiMediaType struct {
+	Schema *openapiSchema `json:"schema" yaml:"schema"`
+}
+
+type openapiComponents struct {
+	Schemas map[string]*openapiSchema `json:"schemas" yaml:"schemas"`
+}
+
+type openapiSchema struct {
+	Ref                  string                    `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string                    `json:"format,omitempty" yaml:"format,omitempty"`
+	Description          string                    `json:"description,omitempty" yaml:"description,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Items                *openapiSchema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*openapiSchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *openapiSchema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// Schemas of golang types, which are not structures.
+// Unsigned integers have no format, when their values do not fit into int64.
+var goToOpenAPISchemas = map[string]openapiSchema{
+	"string":  {Type: "string"},
+	"bool":    {Type: "boolean"},
+	"int":     {Type: "integer", Format: "int64"},
+	"int64":   {Type: "integer", Format: "int64"},
+	"uint":    {Type: "integer"},
+	"uint64":  {Type: "integer"},
+	"int32":   {Type: "integer", Format: "int32"},
+	"uint32":  {Type: "integer", Format: "int64"},
+	"int16":   {Type: "integer", Format: "int32"},
+	"uint16":  {Type: "integer", Format: "int32"},
+	"int8":    {Type: "integer", Format: "int32"},
+	"uint8":   {Type: "integer", Format: "int32"},
+	"byte":    {Type: "integer", Format: "int32"},
+	"rune":    {Type: "integer", Format: "int32"},
+	"float64": {Type: "number", Format: "double"},
+	"float32": {Type: "number", Format: "float"},
+	"error":   {Type: "string"},
+}
+
+type openapiTemplate struct {
+	Info *GenerationInfo
+
+	doc     *openapiDocument
+	content []byte
+	routes  map[string]*httpRoute
+	structs *structLoader
+	// Go types of component schemas, to detect name collisions.
+	origins map[string]string
+}
+
+func NewOpenAPITemplate(info *GenerationInfo) Template {
+	return &openapiTemplate{
+		Info: info,
+	}
+}
+
+// Renders OpenAPI document for routes of http server.
+//
+//		openapi: 3.0.3
+//		info:
+//		  title: StringService
+//		  version: 1.0.0
+//		paths:
+//		  /count:
+//		    post:
+//		      operationId: Count
+//		      description: Count counts symbols in text.
+//		      requestBody:
+//		        required: true
+//		        content:
+//		          application/json:
+//		            schema:
+//		              $ref: '#/components/schemas/CountRequest'
+//		      responses:
+//		        "200": ...
+//		components:
+//		  schemas:
+//		    CountRequest:
+//		      type: object
+//		      properties:
+//		        text:
+//		          type: string
+//
+func (t *openapiTemplate) Render() write_strategy.Renderer {
+	return textRenderer(t.content)
+}
+
+func (t *openapiTemplate) DefaultPath() string {
+	return t.Info.filePath(OpenAPIFile)
+}
+
+func (t *openapiTemplate) Prepare() error {
+	if err := t.Info.Types.Err(); err != nil {
+		return err
+	}
+	var err error
+	t.routes, err = newHTTPRoutes(t.Info.Iface)
+	if err != nil {
+		return err
+	}
+	t.structs = newStructLoader(t.Info)
+	t.origins = make(map[string]string)
+	t.doc = &openapiDocument{
+		OpenAPI: openapiVersion,
+		Info: openapiInfo{
+			Title:       t.Info.Iface.Name,
+			Description: docsText(t.Info.Iface.Docs),
+			Version:     t.Info.OpenAPIVersion,
+		},
+		Paths:      make(map[string]openapiPathItem),
+		Components: openapiComponents{Schemas: make(map[string]*openapiSchema)},
+	}
+	if err := t.addSchema(problemStructName, pathToHttpConverter(t.Info)+"."+problemStructName, problemSchema()); err != nil {
+		return err
+	}
+	for _, fn := range t.Info.Iface.Methods {
+		if err := t.addMethod(fn); err != nil {
+			return fmt.Errorf("%s: %v", fn.Name, err)
+		}
+	}
+	// Document is marshaled before rendering, because Render can not fail.
+	if t.Info.OpenAPIFormat == OpenAPIFormatJSON {
+		t.content, err = json.MarshalIndent(t.doc, "", "\t")
+		t.content = append(t.content, '\n')
+	} else {
+		t.content, err = yaml.Marshal(t.doc)
+	}
+	return err
+}
+
+func (t *openapiTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateRawFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
+
+// Adds route of method, as it is registered in http server, and schemas of its exchanges.
+// Request schema contains only arguments, which are sent in body.
+func (t *openapiTemplate) addMethod(fn *types.Function) error {
+	route := t.routes[fn.Name]
+	operation := &openapiOperation{
+		OperationID: fn.Name,
+		Description: docsText(fn.Docs),
+		Responses: map[string]*openapiResponse{
+			"default": {
+				Description: "Problem document of error, status of response depends on code of error.",
+				Content:     map[string]*openapiMediaType{problemContentType: {Schema: &openapiSchema{Ref: openapiSchemaPrefix + problemStructName}}},
+			},
+		},
+	}
+	for _, param := range route.Params {
+		s, err := t.schema(param.Field.Type, t.Info.ServiceImportPath)
+		if err != nil {
+			return fmt.Errorf("%s: %v", param.Field.Name, err)
+		}
+		operation.Parameters = append(operation.Parameters, &openapiParameter{
+			Name:     param.Name,
+			In:       string(param.In),
+			Required: param.In == httpParamInPath,
+			Schema:   s,
+		})
+	}
+	if len(route.Body) > 0 {
+		request, err := t.exchangeSchema(requestStructName(fn), route.Body)
+		if err != nil {
+			return err
+		}
+		operation.RequestBody = &openapiRequestBody{
+			Required: true,
+			Content:  map[string]*openapiMediaType{openapiContentJSON: {Schema: request}},
+		}
+	}
+	response, err := t.exchangeSchema(responseStructName(fn), removeErrorIfLast(fn.Results))
+	if err != nil {
+		return err
+	}
+	operation.Responses["200"] = &openapiResponse{
+		Description: "Successful response.",
+		Content:     map[string]*openapiMediaType{openapiContentJSON: {Schema: response}},
+	}
+	item, ok := t.doc.Paths[route.Path]
+	if !ok {
+		item = make(openapiPathItem)
+		t.doc.Paths[route.Path] = item
+	}
+	method := strings.ToLower(route.Method)
+	if _, ok := item[method]; ok {
+		return fmt.Errorf("route %s %s is declared twice", route.Method, route.Path)
+	}
+	item[method] = operation
+	return nil
+}
+
+// Schema of problem document, which is written by http server encoder of errors.
+func problemSchema() *openapiSchema {
+	code := &openapiSchema{Type: "string"}
+	for _, c := range errorCodes {
+		code.Enum = append(code.Enum, c.Value)
+	}
+	return &openapiSchema{
+		Type: "object",
+		Properties: map[string]*openapiSchema{
+			"type":     {Type: "string", Format: "uri"},
+			"title":    {Type: "string"},
+			"status":   {Type: "integer", Format: "int32"},
+			"detail":   {Type: "string"},
+			"instance": {Type: "string", Format: "uri"},
+			"code":     code,
+		},
+	}
+}
+
+// Exchange fields are named as in structField.
+func (t *openapiTemplate) exchangeSchema(name string, fields []types.Variable) (*openapiSchema, error) {
+	schema := &openapiSchema{Type: "object", Properties: make(map[string]*openapiSchema)}
+	if err := t.addSchema(name, name, schema); err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		s, err := t.schema(field.Type, t.Info.ServiceImportPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", field.Name, err)
+		}
+		schema.Properties[jsonFieldName(field.Name, t.Info.JSONNaming)] = s
+	}
+	return &openapiSchema{Ref: openapiSchemaPrefix + name}, nil
+}
+
+func (t *openapiTemplate) addSchema(name, origin string, schema *openapiSchema) error {
+	if existing, ok := t.origins[name]; ok {
+		return fmt.Errorf("schema %s is declared for %s and %s", name, existing, origin)
+	}
+	t.origins[name] = origin
+	t.doc.Components.Schemas[name] = schema
+	return nil
+}
+
+// Returns schema of golang type, declared in package pkg.
+// Structures are added to components and referenced.
+func (t *openapiTemplate) schema(field types.Type, pkg string) (*openapiSchema, error) {
+	switch f := field.(type) {
+	case types.TPointer:
+		return t.schema(f.Next, pkg)
+	case types.TArray:
+		return t.arraySchema(f.Next, pkg)
+	case types.TEllipsis:
+		return t.arraySchema(f.Next, pkg)
+	case types.TMap:
+		value, err := t.schema(f.Value, pkg)
+		if err != nil {
+			return nil, err
+		}
+		return &openapiSchema{Type: "object", AdditionalProperties: value}, nil
+	case types.TInterface:
+		// Any value.
+		return &openapiSchema{}, nil
+	case types.TImport:
+		if f.Import == nil {
+			return t.schema(f.Next, pkg)
+		}
+		return t.namedSchema(f.Next, f.Import.Package)
+	case types.TName:
+		if s, ok := goToOpenAPISchemas[f.TypeName]; ok {
+			return &s, nil
+		}
+		return t.namedSchema(f, pkg)
+	}
+	return nil, fmt.Errorf("unsupported type %s", field.String())
+}
+
+func (t *openapiTemplate) arraySchema(elem types.Type, pkg string) (*openapiSchema, error) {
+	// encoding/json encodes byte slices as base64 strings.
+	if name, ok := elem.(types.TName); ok && name.TypeName == "byte" {
+		return &openapiSchema{Type: "string", Format: "byte"}, nil
+	}
+	items, err := t.schema(elem, pkg)
+	if err != nil {
+		return nil, err
+	}
+	return &openapiSchema{Type: "array", Items: items}, nil
+}
+
+func (t *openapiTemplate) namedSchema(field types.Type, pkg string) (*openapiSchema, error) {
+	name, ok := field.(types.TName)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %s", field.String())
+	}
+	switch pkg + "." + name.TypeName {
+	case "time.Time":
+		return &openapiSchema{Type: "string", Format: "date-time"}, nil
+	case "time.Duration":
+		return &openapiSchema{Type: "integer", Format: "int64", Description: "Duration in nanoseconds."}, nil
+	}
+	origin := pkg + "." + name.TypeName
+	named := t.Info.Types.Lookup(pkg, name.TypeName)
+	if err := checkTransportable(named, origin); err != nil {
+		return nil, err
+	}
+	// Named basic types are described by schemas of underlying types with values of enums.
+	if basic := basicType(named); basic != "" {
+		s, ok := goToOpenAPISchemas[basic]
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", origin)
+		}
+		for _, v := range named.Values {
+			s.Enum = append(s.Enum, v.Value)
+		}
+		return &s, nil
+	}
+	if named != nil && named.Kind == InterfaceKind {
+		// Any value.
+		return &openapiSchema{}, nil
+	}
+	ref := &openapiSchema{Ref: openapiSchemaPrefix + name.TypeName}
+	if existing, ok := t.origins[name.TypeName]; ok {
+		if existing != origin {
+			return nil, fmt.Errorf("schema %s is declared for %s and %s", name.TypeName, existing, origin)
+		}
+		return ref, nil
+	}
+	schema := &openapiSchema{Type: "object"}
+	if err := t.addSchema(name.TypeName, origin, schema); err != nil {
+		return nil, err
+	}
+	str, err := t.structs.find(pkg, name.TypeName)
+	if err != nil {
+		return nil, err
+	}
+	if str == nil {
+		// Fields are unknown, e.g. it is not a structure.
+		schema.Description = origin
+		return ref, nil
+	}
+	schema.Description = docsText(str.Docs)
+	schema.Properties = make(map[string]*openapiSchema)
+	for _, field := range str.Fields {
+		if field.Name == "" || !isExported(field.Name) || isJSONIgnored(field) {
+			continue
+		}
+		s, err := t.schema(field.Type, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", origin, field.Name, err)
+		}
+		schema.Properties[jsonTagName(field)] = s
+	}
+	return ref, nil
+}
@@ -0,0 +1,66 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	userAge := types.Variable{Base: types.Base{Name: "userAge"}, Type: types.TName{TypeName: "int"}}
+	tags := types.Variable{Base: types.Base{Name: "tags"}, Type: types.TArray{IsSlice: true, Next: types.TName{TypeName: "string"}}}
+	size := types.Variable{Base: types.Base{Name: "size"}, Type: types.TName{TypeName: "uint64"}}
+	info := protoTestInfo(t, dir, userAge, tags, size)
+	info.Iface.Methods[0].Docs = []string{"// Count counts symbols.", "// @logs-ignore err"}
+	info.OpenAPIFormat = OpenAPIFormatJSON
+
+	tmpl := NewOpenAPITemplate(info)
+	if err := tmpl.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Render().Render(buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc openapiDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	route := doc.Paths["/count"]["post"]
+	if route == nil {
+		t.Fatalf("route /count is not described:\n%s", buf.String())
+	}
+	if route.Description != "Count counts symbols." {
+		t.Errorf("description: %q", route.Description)
+	}
+	if ref := route.RequestBody.Content[openapiContentJSON].Schema.Ref; ref != "#/components/schemas/CountRequest" {
+		t.Errorf("request schema: %s", ref)
+	}
+	request := doc.Components.Schemas["CountRequest"]
+	if request == nil {
+		t.Fatalf("CountRequest schema is missing:\n%s", buf.String())
+	}
+	if s := request.Properties["user_age"]; s == nil || s.Type != "integer" || s.Format != "int64" {
+		t.Errorf("user_age: %+v", s)
+	}
+	if s := request.Properties["size"]; s == nil || s.Type != "integer" || s.Format != "" {
+		t.Errorf("size: %+v", s)
+	}
+	if s := request.Properties["tags"]; s == nil || s.Type != "array" || s.Items == nil || s.Items.Type != "string" {
+		t.Errorf("tags: %+v", s)
+	}
+	if ref := route.Responses["default"].Content[problemContentType].Schema.Ref; ref != "#/components/schemas/Problem" {
+		t.Errorf("error schema: %s", ref)
+	}
+}
@@ -0,0 +1,454 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/vetcher/godecl/types"
+)
+
+const (
+	protoEmpty     = "google.protobuf.Empty"
+	protoTimestamp = "google.protobuf.Timestamp"
+	protoDuration  = "google.protobuf.Duration"
+	protoAny       = "google.protobuf.Any"
+)
+
+var (
+	// Files, which should be imported for well known types.
+	protoWellKnownImports = map[string]string{
+		protoEmpty:     "google/protobuf/empty.proto",
+		protoTimestamp: "google/protobuf/timestamp.proto",
+		protoDuration:  "google/protobuf/duration.proto",
+		protoAny:       "google/protobuf/any.proto",
+	}
+)
+
+// Renders text, which is not go code.
+type textRenderer []byte
+
+func (r textRenderer) Render(w io.Writer) error {
+	_, err := w.Write(r)
+	return err
+}
+
+// Returns name of protobuf package: last element of @protobuf path or name of service package.
+func protoPackageName(info *GenerationInfo) string {
+	if info.ProtobufPackage != "" {
+		return path.Base(info.ProtobufPackage)
+	}
+	return info.ServiceImportPackageName
+}
+
+// Field numbers of messages by message and field names.
+//
+//		{"messages": {"CountRequest": {"text": 1, "symbol": 2}}}
+//
+// Numbers of removed fields are kept, so they are never reused.
+type protoLock struct {
+	Messages map[string]map[string]int `json:"messages"`
+}
+
+type protoMessage struct {
+	Name string
+	// Go type, which is described by message, for error messages and comments.
+	Origin   string
+	Fields   []protoField
+	Reserved []protoField
+}
+
+type protoField struct {
+	Name   string
+	Type   string
+	Number int
+}
+
+// Messages of service, which are shared between proto and lock templates.
+type protoSchema struct {
+	Info *GenerationInfo
+
+	prepared bool
+	err      error
+	messages []*protoMessage
+	byName   map[string]*protoMessage
+	imports  map[string]bool
+	structs  *structLoader
+	lock     protoLock
+}
+
+func newProtoSchema(info *GenerationInfo) *protoSchema {
+	return &protoSchema{
+		Info:    info,
+		byName:  make(map[string]*protoMessage),
+		imports: make(map[string]bool),
+		structs: newStructLoader(info),
+	}
+}
+
+// Builds messages for all methods once.
+func (s *protoSchema) prepare() error {
+	if s.prepared {
+		return s.err
+	}
+	s.prepared = true
+	s.err = s.build()
+	return s.err
+}
+
+func (s *protoSchema) build() error {
+	if err := s.Info.Types.Err(); err != nil {
+		return err
+	}
+	if err := s.readLock(); err != nil {
+		return err
+	}
+	for _, fn := range s.Info.Iface.Methods {
+		if err := s.addExchange(requestStructName(fn), removeContextIfFirst(fn.Args)); err != nil {
+			return fmt.Errorf("%s: %v", fn.Name, err)
+		}
+		if err := s.addExchange(responseStructName(fn), removeErrorIfLast(fn.Results)); err != nil {
+			return fmt.Errorf("%s: %v", fn.Name, err)
+		}
+	}
+	for _, msg := range s.messages {
+		s.number(msg)
+	}
+	return nil
+}
+
+func (s *protoSchema) readLock() error {
+	s.lock.Messages = make(map[string]map[string]int)
+	content, err := ioutil.ReadFile(filepath.Join(s.Info.AbsOutPath, s.Info.filePath(ProtoLockFile)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, &s.lock); err != nil {
+		return fmt.Errorf("%s: %v", s.Info.filePath(ProtoLockFile), err)
+	}
+	if s.lock.Messages == nil {
+		s.lock.Messages = make(map[string]map[string]int)
+	}
+	return nil
+}
+
+// Exchanges without fields are replaced with google.protobuf.Empty.
+func (s *protoSchema) addExchange(name string, fields []types.Variable) error {
+	if len(fields) == 0 {
+		s.imports[protoWellKnownImports[protoEmpty]] = true
+		return nil
+	}
+	msg := &protoMessage{Name: name, Origin: name}
+	if err := s.addMessage(msg); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		t, err := s.protoType(field.Type, s.Info.ServiceImportPath)
+		if err != nil {
+			return fmt.Errorf("%s: %v", field.Name, err)
+		}
+		msg.Fields = append(msg.Fields, protoField{Name: field.Name, Type: t})
+	}
+	return nil
+}
+
+func (s *protoSchema) addMessage(msg *protoMessage) error {
+	if existing, ok := s.byName[msg.Name]; ok {
+		return fmt.Errorf("message %s is declared for %s and %s", msg.Name, existing.Origin, msg.Origin)
+	}
+	s.byName[msg.Name] = msg
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Returns protobuf type for golang type, declared in package pkg.
+// Structures become messages.
+func (s *protoSchema) protoType(field types.Type, pkg string) (string, error) {
+	switch f := field.(type) {
+	case types.TPointer:
+		return s.protoType(f.Next, pkg)
+	case types.TArray:
+		return s.repeatedType(f.Next, pkg)
+	case types.TEllipsis:
+		return s.repeatedType(f.Next, pkg)
+	case types.TMap:
+		key, err := s.protoType(f.Key, pkg)
+		if err != nil {
+			return "", err
+		}
+		value, err := s.protoType(f.Value, pkg)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(value, "repeated ") || strings.HasPrefix(value, "map<") {
+			return "", fmt.Errorf("map values can not be slices or maps in proto")
+		}
+		return fmt.Sprintf("map<%s, %s>", key, value), nil
+	case types.TInterface:
+		return s.wellKnown(protoAny), nil
+	case types.TImport:
+		if f.Import == nil {
+			return s.protoType(f.Next, pkg)
+		}
+		return s.namedType(f.Next, f.Import.Package)
+	case types.TName:
+		if scalar, ok := goToProtoScalars[f.TypeName]; ok {
+			return scalar.proto, nil
+		}
+		// Errors are sent as their messages.
+		if f.TypeName == "error" {
+			return "string", nil
+		}
+		return s.namedType(f, pkg)
+	}
+	return "", fmt.Errorf("unsupported type %s", field.String())
+}
+
+func (s *protoSchema) repeatedType(elem types.Type, pkg string) (string, error) {
+	if name, ok := elem.(types.TName); ok && name.TypeName == "byte" {
+		return "bytes", nil
+	}
+	t, err := s.protoType(elem, pkg)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(t, "repeated ") || strings.HasPrefix(t, "map<") {
+		return "", fmt.Errorf("nested slices and slices of maps are not supported in proto")
+	}
+	return "repeated " + t, nil
+}
+
+// Returns message for named type from package pkg.
+func (s *protoSchema) namedType(field types.Type, pkg string) (string, error) {
+	name, ok := field.(types.TName)
+	if !ok {
+		return "", fmt.Errorf("unsupported type %s", field.String())
+	}
+	switch pkg + "." + name.TypeName {
+	case "time.Time":
+		return s.wellKnown(protoTimestamp), nil
+	case "time.Duration":
+		return s.wellKnown(protoDuration), nil
+	}
+	origin := pkg + "." + name.TypeName
+	named := s.Info.Types.Lookup(pkg, name.TypeName)
+	if err := checkTransportable(named, origin); err != nil {
+		return "", err
+	}
+	// Named basic types and enums become scalars of their underlying types.
+	if basic := basicType(named); basic != "" {
+		return s.protoType(types.TName{TypeName: basic}, pkg)
+	}
+	if msg, ok := s.byName[name.TypeName]; ok {
+		if msg.Origin != origin {
+			return "", fmt.Errorf("message %s is declared for %s and %s", name.TypeName, msg.Origin, origin)
+		}
+		return msg.Name, nil
+	}
+	msg := &protoMessage{Name: name.TypeName, Origin: origin}
+	if err := s.addMessage(msg); err != nil {
+		return "", err
+	}
+	str, err := s.structs.find(pkg, name.TypeName)
+	if err != nil {
+		return "", err
+	}
+	if str == nil {
+		// Fields are unknown, e.g. it is not a structure, so message is left empty.
+		return msg.Name, nil
+	}
+	for _, field := range str.Fields {
+		if !isMessageField(field) {
+			continue
+		}
+		t, err := s.protoType(field.Type, pkg)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %v", origin, field.Name, err)
+		}
+		msg.Fields = append(msg.Fields, protoField{Name: field.Name, Type: t})
+	}
+	return msg.Name, nil
+}
+
+func (s *protoSchema) wellKnown(name string) string {
+	s.imports[protoWellKnownImports[name]] = true
+	return name
+}
+
+// Assigns numbers from lock to fields of message and next free numbers to new fields.
+// Fields from lock, which are not presented in message, become reserved.
+func (s *protoSchema) number(msg *protoMessage) {
+	locked, ok := s.lock.Messages[msg.Name]
+	if !ok {
+		locked = make(map[string]int)
+		s.lock.Messages[msg.Name] = locked
+	}
+	max := 0
+	for _, n := range locked {
+		if n > max {
+			max = n
+		}
+	}
+	present := make(map[string]bool)
+	for i := range msg.Fields {
+		present[msg.Fields[i].Name] = true
+		if n, ok := locked[msg.Fields[i].Name]; ok {
+			msg.Fields[i].Number = n
+			continue
+		}
+		max++
+		locked[msg.Fields[i].Name] = max
+		msg.Fields[i].Number = max
+	}
+	for name, n := range locked {
+		if !present[name] {
+			msg.Reserved = append(msg.Reserved, protoField{Name: name, Number: n})
+		}
+	}
+	sort.Slice(msg.Reserved, func(i, j int) bool { return msg.Reserved[i].Number < msg.Reserved[j].Number })
+}
+
+type protoTemplate struct {
+	Info   *GenerationInfo
+	schema *protoSchema
+}
+
+// Returns templates of proto file and its lock file, which share messages.
+func NewProtoTemplates(info *GenerationInfo) []Template {
+	schema := newProtoSchema(info)
+	return []Template{
+		&protoTemplate{Info: info, schema: schema},
+		&protoLockTemplate{Info: info, schema: schema},
+	}
+}
+
+// Renders proto file.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//
+//		syntax = "proto3";
+//
+//		package stringsvc;
+//
+//		import "google/protobuf/empty.proto";
+//
+//		option go_package = "github.com/user/protobuf/stringsvc";
+//
+//		service StringService {
+//		  rpc Count(CountRequest) returns (CountResponse);
+//		  rpc Ping(google.protobuf.Empty) returns (google.protobuf.Empty);
+//		}
+//
+//		message CountRequest {
+//		  string text = 1;
+//		  string symbol = 2;
+//		}
+//
+func (t *protoTemplate) Render() write_strategy.Renderer {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// %s\n// Please, do not edit.\n\n", FileHeader)
+	fmt.Fprintf(buf, "syntax = \"proto3\";\n\npackage %s;\n", protoPackageName(t.Info))
+
+	var imports []string
+	for imp := range t.schema.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	if len(imports) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, imp := range imports {
+		fmt.Fprintf(buf, "import %q;\n", imp)
+	}
+	if t.Info.ProtobufPackage != "" {
+		fmt.Fprintf(buf, "\noption go_package = %q;\n", t.Info.ProtobufPackage)
+	}
+
+	fmt.Fprintf(buf, "\nservice %s {\n", t.Info.Iface.Name)
+	for _, fn := range t.Info.Iface.Methods {
+		fmt.Fprintf(buf, "  rpc %s(%s) returns (%s);\n", fn.Name,
+			t.exchangeName(requestStructName(fn), removeContextIfFirst(fn.Args)),
+			t.exchangeName(responseStructName(fn), removeErrorIfLast(fn.Results)))
+	}
+	buf.WriteString("}\n")
+
+	for _, msg := range t.schema.messages {
+		fmt.Fprintf(buf, "\nmessage %s {\n", msg.Name)
+		for _, field := range msg.Reserved {
+			fmt.Fprintf(buf, "  reserved %d;\n  reserved %q;\n", field.Number, field.Name)
+		}
+		for _, field := range msg.Fields {
+			fmt.Fprintf(buf, "  %s %s = %d;\n", field.Type, field.Name, field.Number)
+		}
+		buf.WriteString("}\n")
+	}
+	return textRenderer(buf.Bytes())
+}
+
+func (t *protoTemplate) exchangeName(name string, fields []types.Variable) string {
+	if len(fields) == 0 {
+		return protoEmpty
+	}
+	return name
+}
+
+func (t *protoTemplate) DefaultPath() string {
+	return t.Info.filePath(ProtoFile)
+}
+
+func (t *protoTemplate) Prepare() error {
+	return t.schema.prepare()
+}
+
+func (t *protoTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateRawFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
+
+type protoLockTemplate struct {
+	Info   *GenerationInfo
+	schema *protoSchema
+}
+
+// Renders lock file with field numbers of all messages, which were ever generated.
+//
+//		{
+//			"messages": {
+//				"CountRequest": {
+//					"symbol": 2,
+//					"text": 1
+//				}
+//			}
+//		}
+//
+func (t *protoLockTemplate) Render() write_strategy.Renderer {
+	content, err := json.MarshalIndent(t.schema.lock, "", "\t")
+	if err != nil {
+		// Lock contains only maps of strings and numbers.
+		panic(err)
+	}
+	return textRenderer(append(content, '\n'))
+}
+
+func (t *protoLockTemplate) DefaultPath() string {
+	return t.Info.filePath(ProtoLockFile)
+}
+
+func (t *protoLockTemplate) Prepare() error {
+	return t.schema.prepare()
+}
+
+func (t *protoLockTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateRawFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
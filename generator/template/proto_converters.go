@@ -0,0 +1,402 @@
+package template
+
+import (
+	"fmt"
+
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+// Shape of golang type, which defines how it is converted to protobuf type.
+type convShape int
+
+const (
+	// Conversion is ambiguous, stub is rendered.
+	shapeUnknown convShape = iota
+	// Conversion is an expression without converter, e.g. int64(value).
+	shapeInline
+	shapeError
+	shapeTime
+	shapeDuration
+	shapeSlice
+	shapeMap
+	// Structure or pointer to structure, which becomes message.
+	shapeStruct
+)
+
+// Converter, which should be rendered.
+type typeConverter struct {
+	name    string
+	typ     types.Type
+	toProto bool
+}
+
+// Queue of converters, which are rendered to one file.
+// Every converter is rendered once, even if it is needed for several types.
+type converterQueue struct {
+	seen    map[string]bool
+	pending []typeConverter
+}
+
+func newConverterQueue() *converterQueue {
+	return &converterQueue{seen: make(map[string]bool)}
+}
+
+func (q *converterQueue) add(name string, typ types.Type, toProto bool) string {
+	if !q.seen[name] {
+		q.seen[name] = true
+		q.pending = append(q.pending, typeConverter{name: name, typ: typ, toProto: toProto})
+	}
+	return name
+}
+
+func isByte(typ types.Type) bool {
+	name, ok := typ.(types.TName)
+	return ok && (name.TypeName == "byte" || name.TypeName == "uint8")
+}
+
+// Reports, that protobuf value has the same golang type, e.g. string or []byte.
+func isSameInProto(typ types.Type) bool {
+	switch f := typ.(type) {
+	case types.TName:
+		return goToProtoScalars[f.TypeName].golang == f.TypeName
+	case types.TArray:
+		return f.IsSlice && (isByte(f.Next) || isSameInProto(f.Next))
+	case types.TEllipsis:
+		return isSameInProto(f.Next)
+	case types.TMap:
+		return isSameInProto(f.Key) && isSameInProto(f.Value)
+	}
+	return false
+}
+
+// Reports, that value is converted by expression without converter:
+// scalars are converted to their protobuf types and values of the same type are used as is.
+func isInlineConvertible(typ types.Type) bool {
+	if name, ok := typ.(types.TName); ok {
+		_, ok := goToProtoScalars[name.TypeName]
+		return ok
+	}
+	return isSameInProto(typ)
+}
+
+// Returns underlying basic type of named type, which is declared as `type Status int`.
+// Well known types, e.g. time.Duration, have own converters.
+func (t *stubGRPCTypeConverterTemplate) basic(typ types.Type) string {
+	if specialTypeConverter(typ) != nil {
+		return ""
+	}
+	basic := basicType(t.Info.Types.Resolve(typ, t.Info.ServiceImportPath))
+	if _, ok := goToProtoScalars[basic]; !ok {
+		return ""
+	}
+	return basic
+}
+
+// Reports, that value is converted by expression without converter, as isInlineConvertible does.
+// Named basic types are converted to protobuf scalars of their underlying types.
+func (t *stubGRPCTypeConverterTemplate) inline(typ types.Type) bool {
+	return isInlineConvertible(typ) || t.basic(typ) != ""
+}
+
+// Renders conversion of value to protobuf type.
+//
+//		int64(value)
+//
+func (t *stubGRPCTypeConverterTemplate) inlineToProto(typ types.Type, value *Statement) *Statement {
+	if basic := t.basic(typ); basic != "" {
+		return Id(goToProtoScalars[basic].golang).Call(value)
+	}
+	return inlineToProto(typ, value)
+}
+
+// Renders conversion of protobuf value to golang type.
+//
+//		entity.Status(protoValue)
+//
+func (t *stubGRPCTypeConverterTemplate) inlineProtoTo(typ types.Type, value *Statement) *Statement {
+	if t.basic(typ) != "" {
+		return fieldType(typ, false).Call(value)
+	}
+	return inlineProtoTo(typ, value)
+}
+
+// Renders conversion of builtin value to protobuf type.
+//
+//		int64(value)
+//
+func inlineToProto(typ types.Type, value *Statement) *Statement {
+	if name, ok := typ.(types.TName); ok && goToProtoScalars[name.TypeName].golang != name.TypeName {
+		return Id(goToProtoScalars[name.TypeName].golang).Call(value)
+	}
+	return value
+}
+
+// Renders conversion of protobuf value to builtin type.
+//
+//		int(protoValue)
+//
+func inlineProtoTo(typ types.Type, value *Statement) *Statement {
+	if name, ok := typ.(types.TName); ok && goToProtoScalars[name.TypeName].golang != name.TypeName {
+		return Id(name.TypeName).Call(value)
+	}
+	return value
+}
+
+// Renders zero value of golang type, which is returned with error.
+func zeroValue(typ types.Type) *Statement {
+	if _, ok := typ.(types.TImport); ok {
+		return fieldType(typ, false).Values()
+	}
+	return Nil()
+}
+
+// Returns structure, which is referenced by qualified type, or nil.
+// Structures are loaded by Prepare, so errors are not expected here.
+func (t *stubGRPCTypeConverterTemplate) findStruct(typ types.Type) *types.Struct {
+	imp, ok := typ.(types.TImport)
+	if !ok || imp.Import == nil {
+		return nil
+	}
+	name, ok := imp.Next.(types.TName)
+	if !ok || imp.Import.Package == "time" {
+		return nil
+	}
+	str, _ := t.structs.find(imp.Import.Package, name.TypeName)
+	return str
+}
+
+// Loads structures, which are reachable from type through fields of other structures.
+func (t *stubGRPCTypeConverterTemplate) loadStructs(typ types.Type, seen map[string]bool) error {
+	switch f := typ.(type) {
+	case types.TImport:
+		if f.Import == nil {
+			return t.loadStructs(f.Next, seen)
+		}
+		name, ok := f.Next.(types.TName)
+		if !ok || f.Import.Package == "time" {
+			return nil
+		}
+		origin := f.Import.Package + "." + name.TypeName
+		if seen[origin] {
+			return nil
+		}
+		seen[origin] = true
+		str, err := t.structs.find(f.Import.Package, name.TypeName)
+		if err != nil || str == nil {
+			return err
+		}
+		for _, field := range str.Fields {
+			if !isMessageField(field) {
+				continue
+			}
+			if err := t.loadStructs(qualifyType(field.Type, f.Import), seen); err != nil {
+				return fmt.Errorf("%s.%s: %v", origin, field.Name, err)
+			}
+		}
+	case types.TPointer:
+		return t.loadStructs(f.Next, seen)
+	case types.TArray:
+		return t.loadStructs(f.Next, seen)
+	case types.TEllipsis:
+		return t.loadStructs(f.Next, seen)
+	case types.TMap:
+		if err := t.loadStructs(f.Key, seen); err != nil {
+			return err
+		}
+		return t.loadStructs(f.Value, seen)
+	}
+	return nil
+}
+
+func (t *stubGRPCTypeConverterTemplate) shape(typ types.Type) convShape {
+	if t.inline(typ) {
+		return shapeInline
+	}
+	switch f := typ.(type) {
+	case types.TName:
+		if f.TypeName == "error" {
+			return shapeError
+		}
+	case types.TImport:
+		name, ok := f.Next.(types.TName)
+		if !ok || f.Import == nil {
+			return shapeUnknown
+		}
+		switch f.Import.Package + "." + name.TypeName {
+		case "time.Time":
+			return shapeTime
+		case "time.Duration":
+			return shapeDuration
+		}
+		if t.findStruct(f) != nil {
+			return shapeStruct
+		}
+	case types.TPointer:
+		if f.NumberOfPointers == 1 && t.findStruct(f.Next) != nil {
+			return shapeStruct
+		}
+	case types.TArray:
+		if f.IsSlice {
+			return shapeSlice
+		}
+	case types.TEllipsis:
+		return shapeSlice
+	case types.TMap:
+		if t.inline(f.Key) {
+			return shapeMap
+		}
+	}
+	return shapeUnknown
+}
+
+// Renders conversion of value to protobuf type. When converter is needed,
+// its result is assigned to variable tmp and converter is added to queue.
+//
+//		convRelates, err := PtrEntityCommentToProto(value.Relates)
+//		if err != nil {
+//			return nil, err
+//		}
+//
+func (t *stubGRPCTypeConverterTemplate) valueToProto(g *Group, tmp string, value *Statement, typ types.Type, zero *Statement, q *converterQueue) *Statement {
+	if t.inline(typ) {
+		return t.inlineToProto(typ, value)
+	}
+	g.List(Id(tmp), Err()).Op(":=").Id(q.add(typeToProto(typ, 0), typ, true)).Call(value)
+	g.If(Err().Op("!=").Nil()).Block(Return(zero, Err()))
+	return Id(tmp)
+}
+
+// Renders conversion of protobuf value to golang type, as valueToProto does.
+func (t *stubGRPCTypeConverterTemplate) valueProtoTo(g *Group, tmp string, value *Statement, typ types.Type, zero *Statement, q *converterQueue) *Statement {
+	if t.inline(typ) {
+		return t.inlineProtoTo(typ, value)
+	}
+	g.List(Id(tmp), Err()).Op(":=").Id(q.add(protoToType(typ, 0), typ, false)).Call(value)
+	g.If(Err().Op("!=").Nil()).Block(Return(zero, Err()))
+	return Id(tmp)
+}
+
+// Returns import of structure type and structure itself.
+func (t *stubGRPCTypeConverterTemplate) structOf(typ types.Type) (types.TImport, *types.Struct) {
+	if ptr, ok := typ.(types.TPointer); ok {
+		typ = ptr.Next
+	}
+	imp := typ.(types.TImport)
+	return imp, t.findStruct(imp)
+}
+
+// Renders body of converter from golang type to protobuf type.
+func (t *stubGRPCTypeConverterTemplate) toProtoBody(g *Group, typ types.Type, q *converterQueue) {
+	value := func() *Statement { return Id("value") }
+	switch t.shape(typ) {
+	case shapeInline:
+		g.Return(t.inlineToProto(typ, value()), Nil())
+	case shapeError:
+		g.If(value().Op("==").Nil()).Block(Return(Lit(""), Nil()))
+		g.Return(value().Dot("Error").Call(), Nil())
+	case shapeTime:
+		g.Return(Qual(GolangProtobufPtypes, "TimestampProto").Call(value()))
+	case shapeDuration:
+		g.Return(Qual(GolangProtobufPtypes, "DurationProto").Call(value()), Nil())
+	case shapeSlice:
+		g.If(value().Op("==").Nil()).Block(Return(Nil(), Nil()))
+		g.Id("converted").Op(":=").Make(t.protoFieldType(typ), Len(value()))
+		g.For(List(Id("i"), Id("elem")).Op(":=").Range().Add(value())).BlockFunc(func(g *Group) {
+			elem := t.valueToProto(g, "convElem", Id("elem"), elemType(typ), Nil(), q)
+			g.Id("converted").Index(Id("i")).Op("=").Add(elem)
+		})
+		g.Return(Id("converted"), Nil())
+	case shapeMap:
+		m := typ.(types.TMap)
+		g.If(value().Op("==").Nil()).Block(Return(Nil(), Nil()))
+		g.Id("converted").Op(":=").Make(t.protoFieldType(typ), Len(value()))
+		g.For(List(Id("key"), Id("elem")).Op(":=").Range().Add(value())).BlockFunc(func(g *Group) {
+			elem := t.valueToProto(g, "convElem", Id("elem"), m.Value, Nil(), q)
+			g.Id("converted").Index(t.inlineToProto(m.Key, Id("key"))).Op("=").Add(elem)
+		})
+		g.Return(Id("converted"), Nil())
+	case shapeStruct:
+		imp, str := t.structOf(typ)
+		if isPointer(typ) {
+			g.If(value().Op("==").Nil()).Block(Return(Nil(), Nil()))
+		}
+		fields := Dict{}
+		for _, field := range str.Fields {
+			if !isMessageField(field) {
+				continue
+			}
+			name := util.ToUpperFirst(field.Name)
+			fieldTyp := qualifyType(field.Type, imp.Import)
+			fields[Id(name)] = t.valueToProto(g, "conv"+name, value().Dot(field.Name), fieldTyp, Nil(), q)
+		}
+		g.Return(Op("&").Qual(t.Info.ProtobufPackage, imp.Next.(types.TName).TypeName).Values(fields), Nil())
+	default:
+		g.Panic(Lit("function not provided"))
+	}
+}
+
+// Renders body of converter from protobuf type to golang type.
+func (t *stubGRPCTypeConverterTemplate) protoToBody(g *Group, typ types.Type, q *converterQueue) {
+	value := func() *Statement { return Id("protoValue") }
+	switch t.shape(typ) {
+	case shapeInline:
+		g.Return(t.inlineProtoTo(typ, value()), Nil())
+	case shapeError:
+		g.If(value().Op("==").Lit("")).Block(Return(Nil(), Nil()))
+		g.Return(Qual(PackagePathErrors, "New").Call(value()), Nil())
+	case shapeTime:
+		g.Return(Qual(GolangProtobufPtypes, "Timestamp").Call(value()))
+	case shapeDuration:
+		g.Return(Qual(GolangProtobufPtypes, "Duration").Call(value()))
+	case shapeSlice:
+		g.If(value().Op("==").Nil()).Block(Return(Nil(), Nil()))
+		g.Id("converted").Op(":=").Make(fieldType(typ, false), Len(value()))
+		g.For(List(Id("i"), Id("elem")).Op(":=").Range().Add(value())).BlockFunc(func(g *Group) {
+			elem := t.valueProtoTo(g, "convElem", Id("elem"), elemType(typ), Nil(), q)
+			g.Id("converted").Index(Id("i")).Op("=").Add(elem)
+		})
+		g.Return(Id("converted"), Nil())
+	case shapeMap:
+		m := typ.(types.TMap)
+		g.If(value().Op("==").Nil()).Block(Return(Nil(), Nil()))
+		g.Id("converted").Op(":=").Make(fieldType(typ, false), Len(value()))
+		g.For(List(Id("key"), Id("elem")).Op(":=").Range().Add(value())).BlockFunc(func(g *Group) {
+			elem := t.valueProtoTo(g, "convElem", Id("elem"), m.Value, Nil(), q)
+			g.Id("converted").Index(t.inlineProtoTo(m.Key, Id("key"))).Op("=").Add(elem)
+		})
+		g.Return(Id("converted"), Nil())
+	case shapeStruct:
+		imp, str := t.structOf(typ)
+		zero := zeroValue(typ)
+		g.If(value().Op("==").Nil()).Block(Return(zero, Nil()))
+		fields := Dict{}
+		for _, field := range str.Fields {
+			if !isMessageField(field) {
+				continue
+			}
+			name := util.ToUpperFirst(field.Name)
+			fieldTyp := qualifyType(field.Type, imp.Import)
+			fields[Id(field.Name)] = t.valueProtoTo(g, "conv"+name, value().Dot(name), fieldTyp, zero, q)
+		}
+		result := Qual(imp.Import.Package, imp.Next.(types.TName).TypeName).Values(fields)
+		if isPointer(typ) {
+			result = Op("&").Add(result)
+		}
+		g.Return(result, Nil())
+	default:
+		g.Panic(Lit("function not provided"))
+	}
+}
+
+// Returns type of elements of slice.
+func elemType(typ types.Type) types.Type {
+	switch f := typ.(type) {
+	case types.TArray:
+		return f.Next
+	case types.TEllipsis:
+		return f.Next
+	}
+	return typ
+}
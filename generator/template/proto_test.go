@@ -0,0 +1,114 @@
+package template
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func protoTestInfo(t *testing.T, dir string, args ...types.Variable) *GenerationInfo {
+	ctx := types.Variable{Base: types.Base{Name: "ctx"}, Type: types.TImport{Import: &types.Import{Package: PackagePathContext}, Next: types.TName{TypeName: "Context"}}}
+	errVar := types.Variable{Base: types.Base{Name: "err"}, Type: types.TName{TypeName: "error"}}
+	return &GenerationInfo{
+		Iface: &types.Interface{
+			Base: types.Base{Name: "StringService"},
+			Methods: []*types.Function{
+				{Base: types.Base{Name: "Count"}, Args: append([]types.Variable{ctx}, args...), Results: []types.Variable{errVar}},
+			},
+		},
+		ServiceImportPackageName: "stringsvc",
+		ServiceImportPath:        "github.com/user/stringsvc",
+		OutImportPath:            "github.com/user/stringsvc",
+		AbsOutPath:               dir,
+		SourceFilePath:           filepath.Join(dir, "service.go"),
+	}
+}
+
+func renderProto(t *testing.T, info *GenerationInfo) (proto, lock string) {
+	templates := NewProtoTemplates(info)
+	var out []string
+	for _, tmpl := range templates {
+		if err := tmpl.Prepare(); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		if err := tmpl.Render().Render(buf); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, buf.String())
+	}
+	return out[0], out[1]
+}
+
+func TestProtoFieldNumbersAreStable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	text := types.Variable{Base: types.Base{Name: "text"}, Type: types.TName{TypeName: "string"}}
+	symbol := types.Variable{Base: types.Base{Name: "symbol"}, Type: types.TName{TypeName: "string"}}
+	limit := types.Variable{Base: types.Base{Name: "limit"}, Type: types.TName{TypeName: "int"}}
+
+	proto, lock := renderProto(t, protoTestInfo(t, dir, text, symbol))
+	for _, line := range []string{
+		"  rpc Count(CountRequest) returns (google.protobuf.Empty);\n",
+		"import \"google/protobuf/empty.proto\";\n",
+		"  string text = 1;\n  string symbol = 2;\n",
+	} {
+		if !strings.Contains(proto, line) {
+			t.Errorf("proto does not contain %q:\n%s", line, proto)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "string_service.proto.lock"), []byte(lock), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove text and add limit: symbol keeps its number, text number is reserved.
+	proto, _ = renderProto(t, protoTestInfo(t, dir, symbol, limit))
+	for _, line := range []string{
+		"  reserved 1;\n  reserved \"text\";\n",
+		"  string symbol = 2;\n  int64 limit = 3;\n",
+	} {
+		if !strings.Contains(proto, line) {
+			t.Errorf("proto does not contain %q:\n%s", line, proto)
+		}
+	}
+}
+
+func TestProtoScalarsMatchConverters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	flag := types.Variable{Base: types.Base{Name: "flag"}, Type: types.TName{TypeName: "byte"}}
+	level := types.Variable{Base: types.Base{Name: "level"}, Type: types.TName{TypeName: "int8"}}
+	ratio := types.Variable{Base: types.Base{Name: "ratio"}, Type: types.TName{TypeName: "float64"}}
+	info := protoTestInfo(t, dir, flag, level, ratio)
+	info.ProtobufPackage = "github.com/user/stringsvc/pb"
+
+	proto, _ := renderProto(t, info)
+	if line := "  uint32 flag = 1;\n  int32 level = 2;\n  double ratio = 3;\n"; !strings.Contains(proto, line) {
+		t.Errorf("proto does not contain %q:\n%s", line, proto)
+	}
+	converters := renderTemplate(t, NewGRPCEndpointConverterTemplate(info))
+	for _, line := range []string{
+		"Flag:  uint32(req.Flag)",
+		"Level: int32(req.Level)",
+		"Ratio: req.Ratio",
+		"Flag:  byte(req.Flag)",
+		"Level: int8(req.Level)",
+	} {
+		if !strings.Contains(converters, line) {
+			t.Errorf("converters do not contain %q:\n%s", line, converters)
+		}
+	}
+}
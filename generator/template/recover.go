@@ -17,12 +17,12 @@ type recoverTemplate struct {
 
 func NewRecoverTemplate(info *GenerationInfo) Template {
 	return &recoverTemplate{
-		Info: info,
+		Info: info.qualified(),
 	}
 }
 
 func (t *recoverTemplate) Render() write_strategy.Renderer {
-	f := NewFile("middleware")
+	f := t.Info.newFile(MiddlewarePackage)
 	f.PackageComment(FileHeader)
 	f.PackageComment(`Please, do not edit.`)
 
@@ -47,8 +47,8 @@ func (t *recoverTemplate) Render() write_strategy.Renderer {
 	return f
 }
 
-func (recoverTemplate) DefaultPath() string {
-	return "./middleware/recovering.go"
+func (t *recoverTemplate) DefaultPath() string {
+	return t.Info.filePath(RecoveringFile)
 }
 
 func (t *recoverTemplate) Prepare() error {
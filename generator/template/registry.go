@@ -0,0 +1,108 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TagInfo describes templates, which are generated, when tag is listed after @microgen.
+type TagInfo struct {
+	Name        string
+	Description string
+	// Returns templates of tag for interface.
+	Templates func(info *GenerationInfo) []Template
+	// Tags, which are generated together with tag, e.g. `grpc` requires `grpc-converters`.
+	Requires []string
+	// Tags, which can not be generated together with tag.
+	Conflicts []string
+}
+
+var registry = make(map[string]*TagInfo)
+
+// Registers tag, so it can be used after @microgen.
+// Tags of other packages should be registered from init functions, registry is not safe for concurrent use.
+// Returns error, when tag with the same name is already registered.
+func RegisterTag(tag *TagInfo) error {
+	if tag.Name == "" {
+		return fmt.Errorf("tag without name")
+	}
+	if tag.Templates == nil {
+		return fmt.Errorf("tag %s: templates are not provided", tag.Name)
+	}
+	if _, ok := registry[tag.Name]; ok {
+		return fmt.Errorf("tag %s is already registered", tag.Name)
+	}
+	registry[tag.Name] = tag
+	return nil
+}
+
+// Registers tag and panics, when it can not be registered.
+func MustRegisterTag(tag *TagInfo) {
+	if err := RegisterTag(tag); err != nil {
+		panic(err)
+	}
+}
+
+// Returns registered tag by name.
+func LookupTag(name string) (*TagInfo, bool) {
+	tag, ok := registry[name]
+	return tag, ok
+}
+
+// Returns all registered tags, sorted by names.
+func RegisteredTags() []*TagInfo {
+	tags := make([]*TagInfo, 0, len(registry))
+	for _, tag := range registry {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags
+}
+
+// Returns registered tags with all required tags in order of generation:
+// every tag is followed by tags, which it requires. Names, which are not registered, are returned separately.
+// Fails, when required tag is not registered or some tags conflict.
+//
+//		grpc, logging -> grpc, grpc-converters, mock, logging, middleware
+//
+func ResolveTags(names []string) (tags []*TagInfo, unknown []string, err error) {
+	added := make(map[string]bool)
+	var add func(tag *TagInfo) error
+	add = func(tag *TagInfo) error {
+		if added[tag.Name] {
+			return nil
+		}
+		added[tag.Name] = true
+		tags = append(tags, tag)
+		for _, name := range tag.Requires {
+			required, ok := registry[name]
+			if !ok {
+				return fmt.Errorf("tag %s requires unknown tag %s", tag.Name, name)
+			}
+			if err := add(required); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range names {
+		tag, ok := registry[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if err := add(tag); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, tag := range tags {
+		for _, name := range tag.Conflicts {
+			if added[name] {
+				return nil, nil, fmt.Errorf("tags %s and %s can not be used together", tag.Name, name)
+			}
+		}
+	}
+	return tags, unknown, nil
+}
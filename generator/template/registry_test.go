@@ -0,0 +1,54 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveTags(t *testing.T) {
+	tags, unknown, err := ResolveTags([]string{GrpcTag, LoggingMiddlewareTag, "unexpected", MockTag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if got, want := strings.Join(names, ","), "grpc,grpc-converters,mock,logging,middleware"; got != want {
+		t.Errorf("resolved %s, expected %s", got, want)
+	}
+	if len(unknown) != 1 || unknown[0] != "unexpected" {
+		t.Errorf("unexpected unknown tags: %v", unknown)
+	}
+}
+
+func TestRegisterTag(t *testing.T) {
+	none := func(*GenerationInfo) []Template { return nil }
+	for _, tag := range []*TagInfo{
+		{Name: "test-a", Templates: none, Conflicts: []string{"test-b"}},
+		{Name: "test-b", Templates: none},
+		{Name: "test-c", Templates: none, Requires: []string{"test-b"}},
+		{Name: "test-d", Templates: none, Requires: []string{"test-unknown"}},
+	} {
+		if err := RegisterTag(tag); err != nil {
+			t.Fatal(err)
+		}
+		defer delete(registry, tag.Name)
+	}
+
+	if err := RegisterTag(&TagInfo{Name: "test-a", Templates: none}); err == nil {
+		t.Error("duplicate tag is registered")
+	}
+	if err := RegisterTag(&TagInfo{Name: "test-e"}); err == nil {
+		t.Error("tag without templates is registered")
+	}
+	if _, _, err := ResolveTags([]string{"test-a", "test-c"}); err == nil || !strings.Contains(err.Error(), "can not be used together") {
+		t.Errorf("conflict of required tag is not reported: %v", err)
+	}
+	if _, _, err := ResolveTags([]string{"test-d"}); err == nil || !strings.Contains(err.Error(), "test-unknown") {
+		t.Errorf("unknown required tag is not reported: %v", err)
+	}
+	if _, ok := LookupTag("test-b"); !ok {
+		t.Error("registered tag is not found")
+	}
+}
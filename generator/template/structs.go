@@ -0,0 +1,119 @@
+package template
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+// Finds structures, which are used in service methods, in source code of their packages.
+type structLoader struct {
+	info *GenerationInfo
+	// Structures of packages by import path.
+	structs map[string][]types.Struct
+}
+
+func newStructLoader(info *GenerationInfo) *structLoader {
+	return &structLoader{
+		info:    info,
+		structs: make(map[string][]types.Struct),
+	}
+}
+
+// Returns structure declared in package or nil, if it was not found.
+func (l *structLoader) find(pkg, name string) (*types.Struct, error) {
+	structs, ok := l.structs[pkg]
+	if !ok {
+		var err error
+		structs, err = l.load(pkg)
+		if err != nil {
+			return nil, err
+		}
+		l.structs[pkg] = structs
+	}
+	for i := range structs {
+		if structs[i].Name == name {
+			return &structs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Parses all non-test go files of package.
+// Packages, which directories can not be found, have no structures.
+func (l *structLoader) load(pkg string) ([]types.Struct, error) {
+	sourceDir := filepath.Dir(l.info.SourceFilePath)
+	dir := sourceDir
+	if pkg != l.info.ServiceImportPath {
+		var ok bool
+		dir, ok = util.PackageDir(pkg, sourceDir)
+		if !ok {
+			return nil, nil
+		}
+	}
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filenames)
+	var structs []types.Struct
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		file, err := util.ParseFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, file.Structures...)
+	}
+	return structs, nil
+}
+
+func isExported(name string) bool {
+	return name != "" && strings.ToUpper(name[:1]) == name[:1]
+}
+
+// Reports, that field of structure becomes field of protobuf message.
+// Embedded, unexported and ignored by json fields are skipped.
+func isMessageField(field types.StructField) bool {
+	return field.Name != "" && isExported(field.Name) && !isJSONIgnored(field)
+}
+
+func isJSONIgnored(field types.StructField) bool {
+	tags := field.Tags["json"]
+	return len(tags) > 0 && tags[0] == "-"
+}
+
+// Returns name of field in json, as encoding/json does.
+func jsonTagName(field types.StructField) string {
+	tags := field.Tags["json"]
+	if len(tags) > 0 && tags[0] != "" {
+		return tags[0]
+	}
+	return field.Name
+}
+
+// Returns text of docs without comment marks and microgen tags.
+//
+//		// Count counts symbols in text.
+//		// @logs-ignore ans
+//
+// becomes `Count counts symbols in text.`
+func docsText(docs []string) string {
+	var lines []string
+	for _, doc := range docs {
+		if strings.HasPrefix(doc, TagMark) {
+			continue
+		}
+		line := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(doc, "//"), "/*"))
+		line = strings.TrimSpace(strings.TrimSuffix(line, "*/"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
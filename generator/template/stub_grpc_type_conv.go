@@ -2,31 +2,30 @@ package template
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	. "github.com/dave/jennifer/jen"
 	"github.com/devimteam/microgen/generator/write_strategy"
-	"github.com/devimteam/microgen/util"
 	"github.com/vetcher/godecl/types"
 )
 
 const (
 	GolangProtobufPtypesTimestamp = "github.com/golang/protobuf/ptypes/timestamp"
+	GolangProtobufPtypesDuration  = "github.com/golang/protobuf/ptypes/duration"
+	GolangProtobufPtypesAny       = "github.com/golang/protobuf/ptypes/any"
 	JsonbPackage                  = "github.com/sas1024/gorm-jsonb/jsonb"
 	GolangProtobufPtypes          = "github.com/golang/protobuf/ptypes"
 )
 
 type stubGRPCTypeConverterTemplate struct {
-	Info                      *GenerationInfo
-	alreadyRenderedConverters []string
-	state                     WriteStrategyState
+	Info    *GenerationInfo
+	structs *structLoader
 }
 
 func NewStubGRPCTypeConverterTemplate(info *GenerationInfo) Template {
 	return &stubGRPCTypeConverterTemplate{
-		Info: info,
+		Info:    info,
+		structs: newStructLoader(info),
 	}
 }
 
@@ -41,6 +40,10 @@ func specialTypeConverter(p types.Type) *Statement {
 	if name != nil && *name == "Time" && imp != nil && imp.Package == "time" {
 		return (&Statement{}).Op("*").Qual(GolangProtobufPtypesTimestamp, "Timestamp")
 	}
+	// time.Duration -> duration.Duration
+	if name != nil && *name == "Duration" && imp != nil && imp.Package == "time" {
+		return (&Statement{}).Op("*").Qual(GolangProtobufPtypesDuration, "Duration")
+	}
 	// jsonb.JSONB -> string
 	if name != nil && *name == "JSONB" && imp != nil && imp.Package == JsonbPackage {
 		return (&Statement{}).Id("string")
@@ -48,189 +51,179 @@ func specialTypeConverter(p types.Type) *Statement {
 	return nil
 }
 
-func converterToProtoBody(field *types.Variable) Code {
-	s := &Statement{}
-	switch typeToProto(field.Type, 0) {
-	case "ErrorToProto":
-		s.If().Id(util.ToLowerFirst(field.Name)).Op("==").Nil().Block(
-			Return().List(Lit(""), Nil()),
-		).Line()
-		s.Return().List(Id(util.ToLowerFirst(field.Name)).Dot("Error").Call(), Nil())
-	case "ByteListToProto":
-		s.Return().List(Id(util.ToLowerFirst(field.Name)), Nil())
-	case "TimeTimeToProto":
-		s.Return().Qual(GolangProtobufPtypes, "TimestampProto").Call(Id(field.Name))
-	case "ListStringToProto", "SliceStringToProto":
-		s.Return().List(Id(util.ToLowerFirst(field.Name)), Nil())
-	default:
-		s.Panic(Lit("function not provided"))
-	}
-	return s
-}
-
-func converterProtoToBody(field *types.Variable) Code {
-	s := &Statement{}
-	switch protoToType(field.Type, 0) {
-	case "ProtoToError":
-		s.If().Id("proto" + util.ToUpperFirst(field.Name)).Op("==").Lit("").Block(
-			Return().List(Nil(), Nil()),
-		).Line()
-		s.Return().List(Qual("errors", "New").Call(Id("proto"+util.ToUpperFirst(field.Name))), Nil())
-	case "ProtoToByteList":
-		s.Return().List(Id("proto"+util.ToUpperFirst(field.Name)), Nil())
-	case "ProtoToTimeTime":
-		s.Return().Qual(GolangProtobufPtypes, "Timestamp").Call(Id("proto" + util.ToUpperFirst(field.Name)))
-	case "ProtoToListString", "ProtoToSliceString":
-		s.Return().List(Id("proto"+util.ToUpperFirst(field.Name)), Nil())
-	default:
-		s.Panic(Lit("function not provided"))
-	}
-	return s
-}
-
 // Render whole file with protobuf converters.
+// Converters of structures, slices, maps and pointers are synthesized recursively,
+// stubs are rendered only for types, which conversion is ambiguous, e.g. interfaces.
+// Existing file is merged: changed bodies of converters are kept, others are refreshed, signatures are updated,
+// converters, which are not used by interface anymore, are commented out.
 //
 //		// This file was automatically generated by "microgen" utility.
 //		package protobuf
 //
-//		func IntListToProto(positions []int) (protoPositions []int64, convPositionsErr error) {
-//			panic("method not provided")
+//		func ListPtrEntityCommentToProto(value []*entity.Comment) ([]*stringsvc.Comment, error) {
+//			if value == nil {
+//				return nil, nil
+//			}
+//			converted := make([]*stringsvc.Comment, len(value))
+//			for i, elem := range value {
+//				convElem, err := PtrEntityCommentToProto(elem)
+//				if err != nil {
+//					return nil, err
+//				}
+//				converted[i] = convElem
+//			}
+//			return converted, nil
 //		}
 //
-//		func ProtoToIntList(protoPositions []int64) (positions []int, convPositionsErr error) {
-//			panic("method not provided")
+//		func InterfaceToProto(value interface{}) (*any.Any, error) {
+//			panic("function not provided")
 //		}
 //
 func (t *stubGRPCTypeConverterTemplate) Render() write_strategy.Renderer {
 	f := &Statement{}
 
+	q := newConverterQueue()
 	for _, signature := range t.Info.Iface.Methods {
 		args := append(removeContextIfFirst(signature.Args), removeErrorIfLast(signature.Results)...)
 		for _, field := range args {
-			if _, ok := golangTypeToProto("", &field); !ok && !util.IsInStringSlice(typeToProto(field.Type, 0), t.alreadyRenderedConverters) {
-				f.Line().Add(t.stubConverterToProto(&field)).Line()
-				t.alreadyRenderedConverters = append(t.alreadyRenderedConverters, typeToProto(field.Type, 0))
+			typ := qualifyType(field.Type, t.Info.serviceImport())
+			if _, ok := golangTypeToProto("", &field); !ok {
+				q.add(typeToProto(field.Type, 0), typ, true)
 			}
-			if _, ok := protoTypeToGolang("", &field); !ok && !util.IsInStringSlice(protoToType(field.Type, 0), t.alreadyRenderedConverters) {
-				f.Line().Add(t.stubConverterProtoTo(&field)).Line()
-				t.alreadyRenderedConverters = append(t.alreadyRenderedConverters, protoToType(field.Type, 0))
+			if _, ok := protoTypeToGolang("", &field); !ok {
+				q.add(protoToType(field.Type, 0), typ, false)
 			}
 		}
 	}
-
-	if t.state == AppendStrat {
-		return f
+	// Converters of nested types are added to queue while rendering.
+	for i := 0; i < len(q.pending); i++ {
+		conv := q.pending[i]
+		if conv.toProto {
+			f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.converterToProto(conv.name, conv.typ, q)).Line()
+		} else {
+			f.Line().Add(generatedMarker(t.Info.Iface)).Add(t.converterProtoTo(conv.name, conv.typ, q)).Line()
+		}
 	}
 
-	file := NewFile("protobuf")
+	file := t.Info.newFile(GRPCConverterPackage)
 	file.PackageComment(FileHeader)
 	file.PackageComment(`It is better for you if you do not change functions names!`)
-	file.PackageComment(`This file will never be overwritten.`)
+	file.PackageComment(`Bodies of functions, which you changed, are never overwritten.`)
 	file.Add(f)
 
 	return file
 }
 
-func (stubGRPCTypeConverterTemplate) DefaultPath() string {
-	return "./transport/converter/protobuf/type_converters.go"
+func (t *stubGRPCTypeConverterTemplate) DefaultPath() string {
+	return t.Info.filePath(GRPCTypeConvertersFile)
 }
 
 func (t *stubGRPCTypeConverterTemplate) Prepare() error {
 	if t.Info.ProtobufPackage == "" {
 		return fmt.Errorf("protobuf package is empty")
 	}
+	if err := t.Info.Types.Err(); err != nil {
+		return err
+	}
+	// Structures are loaded before rendering, because Render can not fail.
+	seen := make(map[string]bool)
+	for _, signature := range t.Info.Iface.Methods {
+		args := append(removeContextIfFirst(signature.Args), removeErrorIfLast(signature.Results)...)
+		for _, field := range args {
+			if err := t.loadStructs(qualifyType(field.Type, t.Info.serviceImport()), seen); err != nil {
+				return fmt.Errorf("%s: %s: %v", signature.Name, field.Name, err)
+			}
+		}
+	}
 	return nil
 }
 
 func (t *stubGRPCTypeConverterTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
-	if err := util.StatFile(t.Info.AbsOutPath, t.DefaultPath()); os.IsNotExist(err) {
-		t.state = FileStrat
-		return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
-	}
-	file, err := util.ParseFile(filepath.Join(t.Info.AbsOutPath, t.DefaultPath()))
-	if err != nil {
-		return nil, err
-	}
-
-	for i := range file.Functions {
-		t.alreadyRenderedConverters = append(t.alreadyRenderedConverters, file.Functions[i].Name)
-	}
-
-	t.state = AppendStrat
-	return write_strategy.NewAppendToFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+	return write_strategy.NewMergeFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
 }
 
-// Render stub method for golang to protobuf converter.
+// Render golang to protobuf converter.
 //
-//		func IntListToProto(positions []int) (protoPositions []int64, convPositionsErr error) {
-//			return
+//		func ListIntToProto(value []int) ([]int64, error) {
+//			...
 //		}
 //
-func (t *stubGRPCTypeConverterTemplate) stubConverterToProto(field *types.Variable) *Statement {
-	return Func().Id(typeToProto(field.Type, 0)).
-		Params(Id(util.ToLowerFirst(field.Name)).Add(fieldType(field.Type, false))).
-		Params(Add(t.protoFieldType(field.Type)), Error()).
-		Block(converterToProtoBody(field))
+func (t *stubGRPCTypeConverterTemplate) converterToProto(name string, typ types.Type, q *converterQueue) *Statement {
+	return Func().Id(name).
+		Params(Id("value").Add(fieldType(typ, false))).
+		Params(t.protoFieldType(typ), Error()).
+		BlockFunc(func(g *Group) {
+			t.toProtoBody(g, typ, q)
+		})
 }
 
-// Render stub method for protobuf to golang converter.
+// Render protobuf to golang converter.
 //
-//		func ProtoToIntList(protoPositions []int64) (positions []int, convPositionsErr error) {
-//			return
+//		func ProtoToListInt(protoValue []int64) ([]int, error) {
+//			...
 //		}
 //
-func (t *stubGRPCTypeConverterTemplate) stubConverterProtoTo(field *types.Variable) *Statement {
-	return Func().Id(protoToType(field.Type, 0)).
-		Params(Id("proto"+util.ToUpperFirst(field.Name)).Add(t.protoFieldType(field.Type))).
-		Params(Add(fieldType(field.Type, false)), Error()).
-		Block(converterProtoToBody(field))
+func (t *stubGRPCTypeConverterTemplate) converterProtoTo(name string, typ types.Type, q *converterQueue) *Statement {
+	return Func().Id(name).
+		Params(Id("protoValue").Add(t.protoFieldType(typ))).
+		Params(fieldType(typ, false), Error()).
+		BlockFunc(func(g *Group) {
+			t.protoToBody(g, typ, q)
+		})
 }
 
-// Render protobuf field type for given func field.
+// Render protobuf field type for given golang type.
+// Structures become pointers to messages, scalars become types of protobuf scalars.
 //
-//  	*repository.Visit
+//  	[]*stringsvc.Comment
 //
 func (t *stubGRPCTypeConverterTemplate) protoFieldType(field types.Type) *Statement {
-	c := &Statement{}
-	for field != nil {
-		switch f := field.(type) {
-		case types.TImport:
-			if f.Import != nil {
-				c.Qual(t.Info.ProtobufPackage, "")
-			}
-			field = f.Next
-		case types.TName:
-			protoType := f.TypeName
-			if tmp, ok := goToProtoTypesMap[f.TypeName]; ok {
-				protoType = tmp
-			}
-			if code := specialTypeConverter(field); code != nil {
-				return c.Add(code)
-			}
-			c.Id(protoType)
-			field = nil
-		case types.TArray:
-			if f.IsSlice {
-				c.Index()
-			} else if f.ArrayLen > 0 {
-				c.Index(Lit(f.ArrayLen))
-			}
-			field = f.Next
-		case types.TMap:
-			return c.Map(t.protoFieldType(f.Key)).Add(t.protoFieldType(f.Value))
-		case types.TPointer:
-			c.Op(strings.Repeat("*", f.NumberOfPointers))
-			field = f.Next
-		case types.TInterface:
-			mhds := interfaceType(f.Interface)
-			return c.Interface(mhds...)
-		case types.TEllipsis:
-			c.Index()
-			field = f.Next
-		default:
-			return c
+	switch f := field.(type) {
+	case types.TImport:
+		if f.Import == nil {
+			return t.protoFieldType(f.Next)
+		}
+		if code := specialTypeConverter(f); code != nil {
+			return code
+		}
+		name, ok := f.Next.(types.TName)
+		if !ok {
+			return fieldType(f, false)
 		}
+		if basic := t.basic(f); basic != "" {
+			return Id(goToProtoScalars[basic].golang)
+		}
+		if t.findStruct(f) != nil {
+			return Op("*").Qual(t.Info.ProtobufPackage, name.TypeName)
+		}
+		return Qual(t.Info.ProtobufPackage, name.TypeName)
+	case types.TName:
+		if code := specialTypeConverter(f); code != nil {
+			return code
+		}
+		if scalar, ok := goToProtoScalars[f.TypeName]; ok {
+			return Id(scalar.golang)
+		}
+		return Id(f.TypeName)
+	case types.TPointer:
+		if f.NumberOfPointers == 1 && t.findStruct(f.Next) != nil {
+			return t.protoFieldType(f.Next)
+		}
+		return Op(strings.Repeat("*", f.NumberOfPointers)).Add(t.protoFieldType(f.Next))
+	case types.TArray:
+		if f.IsSlice && isByte(f.Next) {
+			return Index().Byte()
+		}
+		if f.IsSlice {
+			return Index().Add(t.protoFieldType(f.Next))
+		}
+		return Index(Lit(f.ArrayLen)).Add(t.protoFieldType(f.Next))
+	case types.TEllipsis:
+		return Index().Add(t.protoFieldType(f.Next))
+	case types.TMap:
+		return Map(t.protoFieldType(f.Key)).Add(t.protoFieldType(f.Value))
+	case types.TInterface:
+		return Op("*").Qual(GolangProtobufPtypesAny, "Any")
 	}
-
-	return c
+	return fieldType(field, false)
 }
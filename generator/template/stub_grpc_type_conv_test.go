@@ -0,0 +1,71 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+const converterTestSource = `package stringsvc
+
+import "time"
+
+type Comment struct {
+	Text     string
+	Likes    int
+	Relates  *Comment
+	PostedAt time.Time
+	Meta     map[string]interface{}
+	secret   string
+	Ignored  string ` + "`json:\"-\"`" + `
+}
+`
+
+func TestTypeConvertersAreSynthesized(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "service.go"), []byte(converterTestSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	comments := types.Variable{
+		Base: types.Base{Name: "comments"},
+		Type: types.TArray{IsSlice: true, Next: types.TPointer{NumberOfPointers: 1, Next: types.TName{TypeName: "Comment"}}},
+	}
+	info := protoTestInfo(t, dir, comments)
+	info.ProtobufPackage = "github.com/user/protobuf/stringsvc"
+
+	out := renderTemplate(t, NewStubGRPCTypeConverterTemplate(info))
+	for _, line := range []string{
+		"func ListPtrCommentToProto(value []*stringsvc.Comment) ([]*stringsvc1.Comment, error) {",
+		"convElem, err := PtrStringsvcCommentToProto(elem)",
+		"func PtrStringsvcCommentToProto(value *stringsvc.Comment) (*stringsvc1.Comment, error) {",
+		"convRelates, err := PtrStringsvcCommentToProto(value.Relates)",
+		"convPostedAt, err := TimeTimeToProto(value.PostedAt)",
+		"Likes:    int64(value.Likes),",
+		"func ProtoToPtrStringsvcComment(protoValue *stringsvc1.Comment) (*stringsvc.Comment, error) {",
+		"Likes:    int(protoValue.Likes),",
+		"return ptypes.Timestamp(protoValue)",
+		// Interfaces are ambiguous, so only their converters are stubs.
+		"func InterfaceToProto(value interface{}) (*any.Any, error) {\n\tpanic(\"function not provided\")\n}",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("converters do not contain %q:\n%s", line, out)
+		}
+	}
+	for _, field := range []string{"secret", "Ignored"} {
+		if strings.Contains(out, "value."+field) {
+			t.Errorf("converters contain field %s:\n%s", field, out)
+		}
+	}
+	if n := strings.Count(out, "func PtrStringsvcCommentToProto("); n != 1 {
+		t.Errorf("converter of recursive structure is rendered %d times", n)
+	}
+}
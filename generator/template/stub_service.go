@@ -14,9 +14,8 @@ import (
 type stubInterfaceTemplate struct {
 	Info *GenerationInfo
 
-	alreadyRenderedMethods []string
-	isStructExist          bool
-	isConstructorExist     bool
+	isStructExist      bool
+	isConstructorExist bool
 }
 
 func NewStubInterfaceTemplate(info *GenerationInfo) Template {
@@ -26,6 +25,8 @@ func NewStubInterfaceTemplate(info *GenerationInfo) Template {
 }
 
 // Renders stub code for service, its methods and constructor, that implements service interface.
+// All methods are rendered, so signatures of already implemented methods are updated by merge,
+// constructor is rendered only when it is missing, because it may have arguments.
 //
 //		// Generated by "microgen" tool.
 //		// Structure stringService implements StringService interface.
@@ -36,6 +37,7 @@ func NewStubInterfaceTemplate(info *GenerationInfo) Template {
 //			panic("constructor not provided")
 //		}
 //
+//		//microgen:generated StringService
 //		func (s *stringService) Count(ctx context.Context, text string, symbol string) (count int, positions []int) {
 //			panic("method not provided")
 //		}
@@ -56,11 +58,9 @@ func (t *stubInterfaceTemplate) Render() write_strategy.Renderer {
 	}
 
 	for _, signature := range t.Info.Iface.Methods {
-		if !util.IsInStringSlice(signature.Name, t.alreadyRenderedMethods) {
-			f.Line().Add(methodDefinition(util.ToLower(t.Info.Iface.Name), signature)).Block(
-				Panic(Lit("method not provided")),
-			).Line()
-		}
+		f.Line().Add(generatedMarker(t.Info.Iface)).Add(methodDefinition(util.ToLower(t.Info.Iface.Name), signature)).Block(
+			Panic(Lit("method not provided")),
+		).Line()
 	}
 	return f
 }
@@ -79,14 +79,6 @@ func (t *stubInterfaceTemplate) Prepare() error {
 		return err
 	}
 
-	// Remove already provided service methods
-	for i := range file.Methods {
-		name := types.TypeName(file.Methods[i].Receiver.Type)
-		if name != nil && *name == util.ToLowerFirst(t.Info.Iface.Name) && types.TypeImport(file.Methods[i].Receiver.Type) == nil {
-			t.alreadyRenderedMethods = append(t.alreadyRenderedMethods, file.Methods[i].Name)
-		}
-	}
-
 	// Remove already provided service structure
 	for i := range file.Structures {
 		if file.Structures[i].Name == util.ToLowerFirst(t.Info.Iface.Name) {
@@ -107,7 +99,7 @@ func (t *stubInterfaceTemplate) Prepare() error {
 }
 
 func (t *stubInterfaceTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
-	return write_strategy.NewAppendToFileStrategy(t.Info.SourceFilePath, t.DefaultPath()), nil
+	return write_strategy.NewMergeFileKeepBodiesStrategy(t.Info.SourceFilePath, t.DefaultPath()), nil
 }
 
 func constructorName(p *types.Interface) string {
@@ -0,0 +1,115 @@
+package template
+
+// Tags of templates, which are provided by microgen.
+func init() {
+	for _, tag := range []*TagInfo{
+		{
+			Name:        MiddlewareTag,
+			Description: "Middleware type of service",
+			Templates:   templates(NewMiddlewareTemplate),
+		},
+		{
+			Name:        LoggingMiddlewareTag,
+			Description: "Logging middleware",
+			Templates:   templates(NewLoggingTemplate),
+			Requires:    []string{MiddlewareTag},
+		},
+		{
+			Name:        RecoverMiddlewareTag,
+			Description: "Middleware, which recovers panics of service",
+			Templates:   templates(NewRecoverTemplate),
+			Requires:    []string{MiddlewareTag},
+		},
+		{
+			Name:        MetricsMiddlewareTag,
+			Description: "Prometheus metrics middleware",
+			Templates:   templates(NewMetricsTemplate),
+			Requires:    []string{MiddlewareTag},
+		},
+		{
+			Name:        TracingMiddlewareTag,
+			Description: "Opentracing middleware",
+			Templates:   templates(NewTracingTemplate),
+			Requires:    []string{MiddlewareTag},
+		},
+		{
+			Name:        HttpTag,
+			Description: "HTTP server, client and their tests",
+			Templates:   templates(NewHttpServerTemplate, NewHttpClientTemplate, NewHTTPTransportTestTemplate),
+			Requires:    []string{HttpConvertersTag, MockTag},
+		},
+		{
+			Name:        HttpServerTag,
+			Description: "HTTP server",
+			Templates:   templates(NewHttpServerTemplate),
+			Requires:    []string{HttpConvertersTag},
+		},
+		{
+			Name:        HttpClientTag,
+			Description: "HTTP client",
+			Templates:   templates(NewHttpClientTemplate),
+			Requires:    []string{HttpConvertersTag},
+		},
+		{
+			Name:        HttpConvertersTag,
+			Description: "Converters of exchanges and errors for HTTP transport",
+			Templates:   templates(NewHttpConverterTemplate, NewHttpErrorsTemplate),
+		},
+		{
+			Name:        GrpcTag,
+			Description: "gRPC server, client and their tests",
+			Templates:   templates(NewGRPCClientTemplate, NewGRPCServerTemplate, NewGRPCTransportTestTemplate),
+			Requires:    []string{GrpcConvertersTag, MockTag},
+		},
+		{
+			Name:        GrpcServerTag,
+			Description: "gRPC server",
+			Templates:   templates(NewGRPCServerTemplate),
+			Requires:    []string{GrpcConvertersTag},
+		},
+		{
+			Name:        GrpcClientTag,
+			Description: "gRPC client",
+			Templates:   templates(NewGRPCClientTemplate),
+			Requires:    []string{GrpcConvertersTag},
+		},
+		{
+			Name:        GrpcConvertersTag,
+			Description: "Protobuf converters of endpoints and types, errors for gRPC transport",
+			Templates:   templates(NewGRPCEndpointConverterTemplate, NewStubGRPCTypeConverterTemplate, NewGRPCErrorsTemplate),
+		},
+		{
+			Name:        MainTag,
+			Description: "Main package, which serves service",
+			Templates:   templates(NewMainTemplate),
+		},
+		{
+			Name:        ProtoTag,
+			Description: "Protobuf definition of service and lock of field numbers",
+			Templates:   NewProtoTemplates,
+		},
+		{
+			Name:        OpenAPITag,
+			Description: "OpenAPI document of HTTP server",
+			Templates:   templates(NewOpenAPITemplate),
+		},
+		{
+			Name:        MockTag,
+			Description: "Mock of service, which is safe for concurrent use",
+			Templates:   templates(NewMockTemplate),
+		},
+	} {
+		MustRegisterTag(tag)
+	}
+}
+
+// Returns function, which creates templates with constructors.
+func templates(constructors ...func(*GenerationInfo) Template) func(*GenerationInfo) []Template {
+	return func(info *GenerationInfo) []Template {
+		tmpls := make([]Template, len(constructors))
+		for i, constructor := range constructors {
+			tmpls[i] = constructor(info)
+		}
+		return tmpls
+	}
+}
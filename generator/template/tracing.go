@@ -0,0 +1,188 @@
+package template
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+const (
+	serviceTracingStructName = "serviceTracing"
+	tracerVarName            = "tracer"
+)
+
+type tracingTemplate struct {
+	Info *GenerationInfo
+}
+
+func NewTracingTemplate(info *GenerationInfo) Template {
+	return &tracingTemplate{
+		Info: info.qualified(),
+	}
+}
+
+// Render all tracing.go file.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//		package middleware
+//
+//		import (
+//			context "context"
+//			svc "github.com/devimteam/microgen/example/svc"
+//			opentracing "github.com/opentracing/opentracing-go"
+//			ext "github.com/opentracing/opentracing-go/ext"
+//		)
+//
+//		func ServiceTracing(tracer opentracing.Tracer) Middleware {
+//			return func(next svc.StringService) svc.StringService {
+//				return &serviceTracing{
+//					next:   next,
+//					tracer: tracer,
+//				}
+//			}
+//		}
+//
+//		type serviceTracing struct {
+//			tracer opentracing.Tracer
+//			next   svc.StringService
+//		}
+//
+//		func (s *serviceTracing) Count(ctx context.Context, text string, symbol string) (count int, err error) {
+//			span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "Count")
+//			defer func() {
+//				if err != nil {
+//					ext.Error.Set(span, true)
+//					span.LogKV("error", err.Error())
+//				}
+//				span.Finish()
+//			}()
+//			return s.next.Count(ctx, text, symbol)
+//		}
+//
+func (t *tracingTemplate) Render() write_strategy.Renderer {
+	f := t.Info.newFile(MiddlewarePackage)
+	f.PackageComment(FileHeader)
+	f.PackageComment(`Please, do not edit.`)
+
+	f.Comment("ServiceTracing starts span for every method call, which is a child of span from context, if it exists.").
+		Line().Func().Id(util.ToUpperFirst(serviceTracingStructName)).Params(Id(tracerVarName).Qual(PackagePathOpenTracing, "Tracer")).Params(Id(MiddlewareTypeName)).
+		Block(t.newTracingBody(t.Info.Iface))
+
+	f.Line()
+
+	f.Type().Id(serviceTracingStructName).Struct(
+		Id(tracerVarName).Qual(PackagePathOpenTracing, "Tracer"),
+		Id(nextVarName).Qual(t.Info.ServiceImportPath, t.Info.Iface.Name),
+	)
+
+	for _, signature := range t.Info.Iface.Methods {
+		f.Line()
+		f.Add(t.tracingFunc(signature)).Line()
+	}
+
+	return f
+}
+
+func (t *tracingTemplate) DefaultPath() string {
+	return t.Info.filePath(TracingFile)
+}
+
+func (t *tracingTemplate) Prepare() error {
+	return nil
+}
+
+func (t *tracingTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
+
+func (t *tracingTemplate) newTracingBody(i *types.Interface) *Statement {
+	return Return(Func().Params(
+		Id(nextVarName).Qual(t.Info.ServiceImportPath, i.Name),
+	).Params(
+		Qual(t.Info.ServiceImportPath, i.Name),
+	).BlockFunc(func(g *Group) {
+		g.Return(Op("&").Id(serviceTracingStructName).Values(
+			Dict{
+				Id(tracerVarName): Id(tracerVarName),
+				Id(nextVarName):   Id(nextVarName),
+			},
+		))
+	}))
+}
+
+func (t *tracingTemplate) tracingFunc(signature *types.Function) *Statement {
+	return methodDefinition(serviceTracingStructName, signature).
+		BlockFunc(t.tracingFuncBody(signature))
+}
+
+// Render tracing function body. Span is passed to next service with context,
+// methods without context as first argument start root spans.
+//
+//		span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, s.tracer, "Count")
+//		defer func() {
+//			if err != nil {
+//				ext.Error.Set(span, true)
+//				span.LogKV("error", err.Error())
+//			}
+//			span.Finish()
+//		}()
+//		return s.next.Count(ctx, text, symbol)
+//
+func (t *tracingTemplate) tracingFuncBody(signature *types.Function) func(g *Group) {
+	receiver := util.LastUpperOrFirst(serviceTracingStructName)
+	return func(g *Group) {
+		if IsContextFirst(signature.Args) && signature.Args[0].Name != "_" {
+			ctx := signature.Args[0].Name
+			g.List(Id("span"), Id(ctx)).Op(":=").Qual(PackagePathOpenTracing, "StartSpanFromContextWithTracer").Call(
+				Id(ctx), Id(receiver).Dot(tracerVarName), Lit(signature.Name),
+			)
+		} else {
+			g.Id("span").Op(":=").Id(receiver).Dot(tracerVarName).Dot("StartSpan").Call(Lit(signature.Name))
+		}
+		g.Defer().Func().Params().BlockFunc(func(body *Group) {
+			if IsErrorLast(signature.Results) {
+				errName := nameOfLastResultError(signature)
+				body.If(Id(errName).Op("!=").Nil()).Block(
+					Qual(PackagePathOpenTracingExt, "Error").Dot("Set").Call(Id("span"), True()),
+					Id("span").Dot("LogKV").Call(Lit("error"), Id(errName).Dot("Error").Call()),
+				)
+			}
+			body.Id("span").Dot("Finish").Call()
+		}).Call()
+
+		g.Return().Id(receiver).Dot(nextVarName).Dot(signature.Name).Call(paramNames(signature.Args))
+	}
+}
+
+// Returns true, when interface has tracing tag. Then transport constructors
+// receive tracer and logger and propagate spans through requests.
+func hasTracing(info *GenerationInfo) bool {
+	return util.IsInStringSlice(TracingMiddlewareTag, util.FetchTags(info.Iface.Docs, TagMark+MicrogenMainTag))
+}
+
+// Renders parameters of transport constructor, which are required for tracing.
+//
+//		tracer opentracing.Tracer, logger log.Logger
+//
+func tracingParams(info *GenerationInfo) *Statement {
+	if !hasTracing(info) {
+		return nil
+	}
+	return List(
+		Id(tracerVarName).Qual(PackagePathOpenTracing, "Tracer"),
+		Id(loggerVarName).Qual(PackagePathGoKitLog, "Logger"),
+	)
+}
+
+// Renders options of transport constructor with tracing option, when interface has tracing tag.
+//
+//		append(opts, http.ServerBefore(opentracing.HTTPToContext(tracer, "Count", logger)))...
+//
+func tracingOpts(info *GenerationInfo, option *Statement) *Statement {
+	if !hasTracing(info) {
+		return Id("opts").Op("...")
+	}
+	return Append(Id("opts"), option).Op("...")
+}
@@ -0,0 +1,41 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestTracing(t *testing.T) {
+	text := types.Variable{Base: types.Base{Name: "text"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, "/tmp/out", text)
+	info.ProtobufPackage = "github.com/user/stringsvc/pb"
+	info.GRPCRegAddr = "stringsvc.StringService"
+
+	// Transports do not depend on tracing without tag.
+	testRender(t, []renderCase{
+		{"http server without tracing", NewHttpServerTemplate(info), []string{
+			"func NewHTTPHandler(endpoints *stringsvc.Endpoints, opts ...http.ServerOption) http1.Handler {",
+		}},
+	})
+
+	info.Iface.Docs = []string{"// @microgen tracing, http, grpc"}
+	testRender(t, []renderCase{
+		{"middleware", NewTracingTemplate(info), []string{
+			".StartSpanFromContextWithTracer(ctx, T.tracer, \"Count\")",
+			"ext.Error.Set(span, true)",
+		}},
+		{"http server", NewHttpServerTemplate(info), []string{
+			"ServerBefore(opentracing.HTTPToContext(tracer, \"Count\", logger)))...",
+		}},
+		{"http client", NewHttpClientTemplate(info), []string{
+			"ClientBefore(opentracing.ContextToHTTP(tracer, logger)))...",
+		}},
+		{"grpc server", NewGRPCServerTemplate(info), []string{
+			"ServerBefore(opentracing.GRPCToContext(tracer, \"Count\", logger)))...",
+		}},
+		{"grpc client", NewGRPCClientTemplate(info), []string{
+			"ClientBefore(opentracing.ContextToGRPC(tracer, logger)))...",
+		}},
+	})
+}
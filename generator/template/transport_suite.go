@@ -0,0 +1,506 @@
+package template
+
+import (
+	"fmt"
+
+	. "github.com/dave/jennifer/jen"
+	"github.com/devimteam/microgen/generator/write_strategy"
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+)
+
+type transportTestTemplate struct {
+	Info *GenerationInfo
+	// Transport: http or grpc.
+	transport string
+	structs   *structLoader
+	// Sample values of arguments and results by methods names.
+	samples map[string]*methodSamples
+}
+
+// Values of arguments and results of method, which are sent through transport.
+type methodSamples struct {
+	args    []*Statement
+	results []*Statement
+}
+
+// Generates test, which calls every method through client and server of http transport.
+func NewHTTPTransportTestTemplate(info *GenerationInfo) Template {
+	return &transportTestTemplate{
+		Info:      info,
+		transport: HttpTag,
+		structs:   newStructLoader(info),
+	}
+}
+
+// Generates test, which calls every method through client and server of grpc transport.
+func NewGRPCTransportTestTemplate(info *GenerationInfo) Template {
+	return &transportTestTemplate{
+		Info:      info,
+		transport: GrpcTag,
+		structs:   newStructLoader(info),
+	}
+}
+
+func (t *transportTestTemplate) DefaultPath() string {
+	if t.transport == GrpcTag {
+		return t.Info.filePath(GRPCTestFile)
+	}
+	return t.Info.filePath(HTTPTestFile)
+}
+
+func (t *transportTestTemplate) ChooseStrategy() (write_strategy.Strategy, error) {
+	return write_strategy.NewCreateFileStrategy(t.Info.AbsOutPath, t.DefaultPath()), nil
+}
+
+// Chooses sample values of all arguments and results.
+// Structures are loaded here, because Render can not fail.
+func (t *transportTestTemplate) Prepare() error {
+	if t.transport == GrpcTag && t.Info.ProtobufPackage == "" {
+		return fmt.Errorf("protobuf package is empty")
+	}
+	t.samples = make(map[string]*methodSamples)
+	gen := &sampleGenerator{structs: t.structs, types: t.Info.Types}
+	for _, signature := range t.signatures() {
+		samples := &methodSamples{}
+		for _, arg := range removeContextIfFirst(signature.Args) {
+			value, err := gen.value(arg.Type, arg.Name, make(map[string]bool))
+			if err != nil {
+				return fmt.Errorf("%s: %s: %v", signature.Name, arg.Name, err)
+			}
+			samples.args = append(samples.args, value)
+		}
+		for _, result := range removeErrorIfLast(signature.Results) {
+			value, err := gen.value(result.Type, result.Name, make(map[string]bool))
+			if err != nil {
+				return fmt.Errorf("%s: %s: %v", signature.Name, result.Name, err)
+			}
+			samples.results = append(samples.results, value)
+		}
+		t.samples[signature.Name] = samples
+	}
+	return nil
+}
+
+// Render whole test file of transport.
+//
+//		// This file was automatically generated by "microgen" utility.
+//		// Please, do not edit.
+//		package transporthttp
+//
+//		// Starts http server of service and returns client, which is connected to it.
+//		func newHTTPTestClient(t *testing.T, svc stringsvc.StringService) (stringsvc.StringService, func()) {
+//			server := httptest.NewServer(NewHTTPHandler(stringsvc.NewEndpoints(svc, nil, nil)))
+//			client, err := NewHTTPClient(server.URL)
+//			if err != nil {
+//				server.Close()
+//				t.Fatal(err)
+//			}
+//			return client, server.Close
+//		}
+//
+//		// TestHTTPTransport checks, that arguments and results of every method are not changed by transport.
+//		func TestHTTPTransport(t *testing.T) {
+//			t.Run("Count", func(t *testing.T) {
+//				...
+//			})
+//		}
+//
+func (t *transportTestTemplate) Render() write_strategy.Renderer {
+	var (
+		f    *File
+		name string
+	)
+	if t.transport == GrpcTag {
+		f = t.Info.newFile(GRPCTransportPackage)
+		name = "GRPC"
+	} else {
+		f = t.Info.newFile(HTTPTransportPackage)
+		name = "HTTP"
+	}
+	f.PackageComment(FileHeader)
+	f.PackageComment(`Please, do not edit.`)
+
+	f.Add(t.newTestClient(name))
+	f.Line()
+	f.Comment("Test"+name+"Transport checks, that arguments and results of every method are not changed by transport.").
+		Line().Func().Id("Test"+name+"Transport").Params(Id("t").Op("*").Qual(PackagePathTesting, "T")).BlockFunc(func(g *Group) {
+		for _, signature := range t.signatures() {
+			g.Add(t.methodTest(signature, "new"+name+"TestClient"))
+		}
+	})
+
+	return f
+}
+
+// Render constructor of client, which is connected to server of service.
+//
+//		// Starts grpc server of service and returns client, which is connected to it.
+//		func newGRPCTestClient(t *testing.T, svc stringsvc.StringService) (stringsvc.StringService, func()) {
+//			listener := bufconn.Listen(1 << 20)
+//			server := grpc.NewServer()
+//			pb.RegisterStringServiceServer(server, NewGRPCServer(stringsvc.NewEndpoints(svc, nil, nil)))
+//			go server.Serve(listener)
+//			conn, err := grpc.Dial("bufconn", grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+//				return listener.Dial()
+//			}), grpc.WithInsecure())
+//			if err != nil {
+//				server.Stop()
+//				t.Fatal(err)
+//			}
+//			return NewGRPCClient(conn), func() {
+//				conn.Close()
+//				server.Stop()
+//			}
+//		}
+//
+func (t *transportTestTemplate) newTestClient(name string) *Statement {
+	service := Qual(t.Info.ServiceImportPath, t.Info.Iface.Name)
+	endpoints := Qual(t.Info.importPath(EndpointPackage), endpointsConstructorName(t.Info)).Call(Id("svc"), Nil(), Nil())
+	return Comment("Starts "+t.transport+" server of service and returns client, which is connected to it.").
+		Line().Func().Id("new"+name+"TestClient").Params(
+		Id("t").Op("*").Qual(PackagePathTesting, "T"),
+		Id("svc").Add(service.Clone()),
+	).Params(service.Clone(), Func().Params()).BlockFunc(func(g *Group) {
+		if t.transport == HttpTag {
+			g.Id("server").Op(":=").Qual(PackagePathHttpTest, "NewServer").Call(Id("NewHTTPHandler").Call(endpoints, t.tracingArgs()))
+			g.List(Id("client"), Err()).Op(":=").Id("NewHTTPClient").Call(Id("server").Dot("URL"), t.tracingArgs())
+			g.If(Err().Op("!=").Nil()).Block(
+				Id("server").Dot("Close").Call(),
+				Id("t").Dot("Fatal").Call(Err()),
+			)
+			g.Return(Id("client"), Id("server").Dot("Close"))
+			return
+		}
+		g.Id("listener").Op(":=").Qual(PackagePathGRPCBufConn, "Listen").Call(Lit(1).Op("<<").Lit(20))
+		g.Id("server").Op(":=").Qual(PackagePathGoogleGRPC, "NewServer").Call()
+		g.Qual(t.Info.ProtobufPackage, "Register"+util.ToUpperFirst(t.Info.Iface.Name)+"Server").Call(
+			Id("server"), Id("NewGRPCServer").Call(endpoints, t.tracingArgs()),
+		)
+		g.Go().Id("server").Dot("Serve").Call(Id("listener"))
+		g.List(Id("conn"), Err()).Op(":=").Qual(PackagePathGoogleGRPC, "Dial").Call(
+			Lit("bufconn"),
+			Qual(PackagePathGoogleGRPC, "WithDialer").Call(
+				Func().Params(String(), Qual(PackagePathTime, "Duration")).Params(Qual(PackagePathNet, "Conn"), Error()).Block(
+					Return(Id("listener").Dot("Dial").Call()),
+				),
+			),
+			Qual(PackagePathGoogleGRPC, "WithInsecure").Call(),
+		)
+		g.If(Err().Op("!=").Nil()).Block(
+			Id("server").Dot("Stop").Call(),
+			Id("t").Dot("Fatal").Call(Err()),
+		)
+		g.Return(Id("NewGRPCClient").Call(Id("conn"), t.tracingArgs()), Func().Params().Block(
+			Id("conn").Dot("Close").Call(),
+			Id("server").Dot("Stop").Call(),
+		))
+	})
+}
+
+// Render test of one method, which compares arguments and results of mock with sent and received ones.
+//
+//		t.Run("Count", func(t *testing.T) {
+//			wantText := "text1"
+//			wantCount := 2
+//			svc := &stringmock.StringService{
+//				CountFunc: func(context.Context, string) (int, error) {
+//					return wantCount, nil
+//				},
+//			}
+//			client, stop := newHTTPTestClient(t, svc)
+//			defer stop()
+//			gotCount, err := client.Count(context.Background(), wantText)
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			calls := svc.CountCalls()
+//			if len(calls) != 1 {
+//				t.Fatalf("Count is called %d times, expected 1", len(calls))
+//			}
+//			if !reflect.DeepEqual(calls[0].Text, wantText) {
+//				t.Errorf("text is %#v, expected %#v", calls[0].Text, wantText)
+//			}
+//			if !reflect.DeepEqual(gotCount, wantCount) {
+//				t.Errorf("count is %#v, expected %#v", gotCount, wantCount)
+//			}
+//		})
+//
+func (t *transportTestTemplate) methodTest(signature *types.Function, newClient string) *Statement {
+	samples := t.samples[signature.Name]
+	args := removeContextIfFirst(signature.Args)
+	results := removeErrorIfLast(signature.Results)
+	errorLast := IsErrorLast(signature.Results)
+	return Id("t").Dot("Run").Call(Lit(signature.Name), Func().Params(Id("t").Op("*").Qual(PackagePathTesting, "T")).BlockFunc(func(g *Group) {
+		// Samples are prefixed, so they do not clash with t, svc, client and other variables of test.
+		for i, arg := range args {
+			g.Id(wantName(arg.Name)).Op(":=").Add(samples.args[i])
+		}
+		for i, result := range results {
+			g.Id(wantName(result.Name)).Op(":=").Add(samples.results[i])
+		}
+		g.Id("svc").Op(":=").Op("&").Qual(t.Info.importPath(MockPackage), t.Info.Iface.Name).Values(Dict{
+			Id(mockFuncName(signature)): Func().ParamsFunc(func(p *Group) {
+				for _, arg := range signature.Args {
+					p.Add(fieldType(arg.Type, true))
+				}
+			}).ParamsFunc(func(p *Group) {
+				for _, result := range signature.Results {
+					p.Add(fieldType(result.Type, false))
+				}
+			}).BlockFunc(func(body *Group) {
+				body.ReturnFunc(func(r *Group) {
+					for _, result := range results {
+						r.Id(wantName(result.Name))
+					}
+					if errorLast {
+						r.Nil()
+					}
+				})
+			}),
+		})
+		g.List(Id("client"), Id("stop")).Op(":=").Id(newClient).Call(Id("t"), Id("svc"))
+		g.Defer().Id("stop").Call()
+
+		call := Id("client").Dot(signature.Name).CallFunc(func(c *Group) {
+			if IsContextFirst(signature.Args) {
+				c.Qual(PackagePathContext, "Background").Call()
+			}
+			for _, arg := range args {
+				v := Id(wantName(arg.Name))
+				if types.IsEllipsis(arg.Type) {
+					v.Op("...")
+				}
+				c.Add(v)
+			}
+		})
+		var got []Code
+		for _, result := range results {
+			got = append(got, Id("got"+util.ToUpperFirst(result.Name)))
+		}
+		if errorLast {
+			got = append(got, Err())
+		}
+		if len(got) > 0 {
+			g.List(got...).Op(":=").Add(call)
+		} else {
+			g.Add(call)
+		}
+		if errorLast {
+			g.If(Err().Op("!=").Nil()).Block(Id("t").Dot("Fatal").Call(Err()))
+		}
+
+		g.Id("calls").Op(":=").Id("svc").Dot(signature.Name + "Calls").Call()
+		g.If(Len(Id("calls")).Op("!=").Lit(1)).Block(
+			Id("t").Dot("Fatalf").Call(Lit(signature.Name+" is called %d times, expected 1"), Len(Id("calls"))),
+		)
+		for _, arg := range args {
+			g.Add(compareValues(arg.Name, Id("calls").Index(Lit(0)).Dot(util.ToUpperFirst(arg.Name))))
+		}
+		for _, result := range results {
+			g.Add(compareValues(result.Name, Id("got"+util.ToUpperFirst(result.Name))))
+		}
+	}))
+}
+
+// Render comparison of received value with sent one.
+//
+//		if !reflect.DeepEqual(gotCount, wantCount) {
+//			t.Errorf("count is %#v, expected %#v", gotCount, wantCount)
+//		}
+//
+func compareValues(name string, got *Statement) *Statement {
+	want := Id(wantName(name))
+	return If(Op("!").Qual(PackagePathReflect, "DeepEqual").Call(got.Clone(), want.Clone())).Block(
+		Id("t").Dot("Errorf").Call(Lit(util.ToLowerFirst(name)+" is %#v, expected %#v"), got.Clone(), want.Clone()),
+	)
+}
+
+// Returns name of variable with sample value of argument or result.
+//
+//		count -> wantCount
+//
+func wantName(name string) string {
+	return "want" + util.ToUpperFirst(name)
+}
+
+// Renders arguments of transport constructors for tracing, which do nothing.
+//
+//		opentracing.NoopTracer{}, log.NewNopLogger()
+//
+func (t *transportTestTemplate) tracingArgs() *Statement {
+	if !hasTracing(t.Info) {
+		return nil
+	}
+	return List(Qual(PackagePathOpenTracing, "NoopTracer").Values(), Qual(PackagePathGoKitLog, "NewNopLogger").Call())
+}
+
+// Returns methods of interface with qualified types, as mock does.
+func (t *transportTestTemplate) signatures() []*types.Function {
+	return (&mockTemplate{Info: t.Info}).signatures()
+}
+
+// Chooses values of types, which are not zero, so lost fields are noticed.
+// Every value is different, so swapped fields are noticed too.
+type sampleGenerator struct {
+	structs *structLoader
+	types   *TypeResolver
+	// Number of generated values.
+	n int
+}
+
+// Returns sample value of type. Name of variable is used for strings.
+// Structures, which are visited, are not filled again, so recursive structures are finite.
+//
+//		[]*entity.Comment{&entity.Comment{Text: "text1", Relates: nil}}
+//
+func (s *sampleGenerator) value(typ types.Type, name string, visiting map[string]bool) (*Statement, error) {
+	switch f := typ.(type) {
+	case types.TName:
+		return s.builtin(f.TypeName, name), nil
+	case types.TImport:
+		if f.Import == nil {
+			return s.value(f.Next, name, visiting)
+		}
+		return s.named(f, name, visiting)
+	case types.TPointer:
+		if f.NumberOfPointers != 1 {
+			return Nil(), nil
+		}
+		str, err := s.findStruct(f.Next)
+		if err != nil {
+			return nil, err
+		}
+		if str != nil && visiting[types.TypeImport(f.Next).Package+"."+str.Name] {
+			return Nil(), nil
+		}
+		value, err := s.value(f.Next, name, visiting)
+		if err != nil {
+			return nil, err
+		}
+		if str != nil {
+			return Op("&").Add(value), nil
+		}
+		// Pointer to value of not composite type.
+		return Func().Params().Add(fieldType(f, false)).Block(
+			Id("v").Op(":=").Add(value),
+			Return(Op("&").Id("v")),
+		).Call(), nil
+	case types.TArray:
+		if f.IsSlice && isByte(f.Next) {
+			s.n++
+			return Index().Byte().Call(Lit(fmt.Sprintf("%s%d", name, s.n))), nil
+		}
+		elem, err := s.value(f.Next, name, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return fieldType(f, false).Values(elem), nil
+	case types.TEllipsis:
+		elem, err := s.value(f.Next, name, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return fieldType(f, false).Values(elem), nil
+	case types.TMap:
+		key, err := s.value(f.Key, name, visiting)
+		if err != nil {
+			return nil, err
+		}
+		value, err := s.value(f.Value, name, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return fieldType(f, false).Values(Dict{key: value}), nil
+	case types.TInterface:
+		return Nil(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", typ.String())
+}
+
+// Returns literal of builtin type.
+//
+//		"text1" or uint(2)
+//
+func (s *sampleGenerator) builtin(typ, name string) *Statement {
+	s.n++
+	switch typ {
+	case "string":
+		return Lit(fmt.Sprintf("%s%d", name, s.n))
+	case "bool":
+		return True()
+	case "int":
+		return Lit(s.n)
+	case "float64":
+		return Lit(float64(s.n) + 0.5)
+	case "float32":
+		return Id(typ).Call(Lit(float64(s.n) + 0.5))
+	case "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune", "uintptr":
+		return Id(typ).Call(Lit(s.n))
+	}
+	// Interfaces and errors.
+	return Nil()
+}
+
+// Returns value of type, declared in other package.
+//
+//		time.Date(2001, time.January, 1, 0, 0, 1, 0, time.UTC)
+//
+func (s *sampleGenerator) named(typ types.TImport, name string, visiting map[string]bool) (*Statement, error) {
+	typeName, ok := typ.Next.(types.TName)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %s", typ.String())
+	}
+	origin := typ.Import.Package + "." + typeName.TypeName
+	switch origin {
+	case "time.Time":
+		s.n++
+		return Qual(PackagePathTime, "Date").Call(
+			Lit(2000+s.n), Qual(PackagePathTime, "January"), Lit(1), Lit(0), Lit(0), Lit(s.n), Lit(0), Qual(PackagePathTime, "UTC"),
+		), nil
+	case "time.Duration":
+		s.n++
+		return Lit(s.n).Op("*").Qual(PackagePathTime, "Second"), nil
+	}
+	named := s.types.Lookup(typ.Import.Package, typeName.TypeName)
+	if basic := basicType(named); basic != "" {
+		return fieldType(typ, false).Call(s.builtin(basic, name)), nil
+	}
+	str, err := s.findStruct(typ)
+	if err != nil {
+		return nil, err
+	}
+	if str == nil {
+		// Zero value of unknown type.
+		return Op("*").New(fieldType(typ, false)), nil
+	}
+	if visiting[origin] {
+		return fieldType(typ, false).Values(), nil
+	}
+	visiting[origin] = true
+	defer delete(visiting, origin)
+	fields := Dict{}
+	for _, field := range str.Fields {
+		if !isMessageField(field) {
+			continue
+		}
+		value, err := s.value(qualifyType(field.Type, typ.Import), util.ToLowerFirst(field.Name), visiting)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", origin, field.Name, err)
+		}
+		fields[Id(field.Name)] = value
+	}
+	return fieldType(typ, false).Values(fields), nil
+}
+
+func (s *sampleGenerator) findStruct(typ types.Type) (*types.Struct, error) {
+	imp, ok := typ.(types.TImport)
+	if !ok || imp.Import == nil {
+		return nil, nil
+	}
+	name, ok := imp.Next.(types.TName)
+	if !ok || imp.Import.Package == "time" {
+		return nil, nil
+	}
+	return s.structs.find(imp.Import.Package, name.TypeName)
+}
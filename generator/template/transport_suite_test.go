@@ -0,0 +1,60 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+func TestTransportTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "service.go"), []byte(converterTestSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	comments := types.Variable{
+		Base: types.Base{Name: "comments"},
+		Type: types.TEllipsis{Next: types.TPointer{NumberOfPointers: 1, Next: types.TName{TypeName: "Comment"}}},
+	}
+	// Argument has the same name as variable of test.
+	svc := types.Variable{Base: types.Base{Name: "svc"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, dir, svc, comments)
+	info.ProtobufPackage = "github.com/user/stringsvc/pb"
+	info.Iface.Methods[0].Results = append([]types.Variable{
+		{Base: types.Base{Name: "count"}, Type: types.TName{TypeName: "int"}},
+	}, info.Iface.Methods[0].Results...)
+
+	// Recursive structure is filled once, ignored fields are skipped.
+	samples := []string{
+		"wantComments := []*stringsvc.Comment{&stringsvc.Comment{",
+		"wantSvc := \"svc",
+		"Relates:  nil,",
+		"CountFunc: func(context.Context, string, ...*stringsvc.Comment) (int, error) {",
+		"gotCount, err := client.Count(context.Background(), wantSvc, wantComments...)",
+		"if !reflect.DeepEqual(calls[0].Comments, wantComments) {",
+	}
+	http := NewHTTPTransportTestTemplate(info)
+	grpc := NewGRPCTransportTestTemplate(info)
+	testRender(t, []renderCase{
+		{"http", http, append([]string{
+			"server := httptest.NewServer(NewHTTPHandler(stringsvc.NewEndpoints(svc, nil, nil)))",
+		}, samples...)},
+		{"grpc", grpc, append([]string{
+			"pb.RegisterStringServiceServer(server, NewGRPCServer(stringsvc.NewEndpoints(svc, nil, nil)))",
+			"listener := bufconn.Listen(1 << 20)",
+		}, samples...)},
+	})
+	for _, tmpl := range []Template{http, grpc} {
+		if out := renderTemplate(t, tmpl); strings.Contains(out, "Ignored") || strings.Contains(out, "secret") {
+			t.Errorf("test contains fields, which are not sent:\n%s", out)
+		}
+	}
+}
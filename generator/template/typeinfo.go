@@ -0,0 +1,240 @@
+package template
+
+import (
+	"fmt"
+	"go/constant"
+	gotypes "go/types"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/devimteam/microgen/util"
+	"github.com/vetcher/godecl/types"
+	"golang.org/x/tools/go/packages"
+)
+
+// Kind of named type, resolved with full type information.
+type TypeKind int
+
+const (
+	UnknownKind TypeKind = iota
+	StructKind
+	InterfaceKind
+	// Named type with basic underlying type, e.g. `type Status int`.
+	BasicKind
+	// Named basic type with constants of this type, declared in its package.
+	EnumKind
+	FuncKind
+	ChanKind
+	// Named slice, array, map or pointer type.
+	CompositeKind
+)
+
+// Information about named type, which is not available in source file of service.
+type NamedType struct {
+	Kind TypeKind
+	// Type is declared as alias, e.g. `type ID = string`. Kind is the kind of aliased type.
+	Alias bool
+	// Name of underlying type of BasicKind and EnumKind, e.g. `int`.
+	Basic string
+	// Constants of EnumKind in order of declaration.
+	Values []EnumValue
+}
+
+type EnumValue struct {
+	Name string
+	// Value of constant: string, int64, uint64, float64 or bool.
+	Value interface{}
+}
+
+// Resolves named types of package and its dependencies with go/packages.
+// Package is loaded once, on first lookup. When package can not be loaded,
+// all types are unknown and templates, which need them, fail with Err.
+type TypeResolver struct {
+	dir string
+
+	once     sync.Once
+	packages map[string]*gotypes.Package
+	// Error of loading, when package can not be typed.
+	err error
+}
+
+var (
+	resolversMx sync.Mutex
+	resolvers   = make(map[string]*TypeResolver)
+)
+
+// Returns resolver of package in directory.
+// Services of one package share resolver, so package is loaded once.
+func TypeResolverFor(dir string) *TypeResolver {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	resolversMx.Lock()
+	defer resolversMx.Unlock()
+	if r, ok := resolvers[dir]; ok {
+		return r
+	}
+	r := &TypeResolver{dir: dir}
+	resolvers[dir] = r
+	return r
+}
+
+// Loads package with types of all its dependencies.
+// Packages with errors in source code are used as well: their types are partially known,
+// errors are printed as warnings.
+func (r *TypeResolver) load() {
+	r.packages = make(map[string]*gotypes.Package)
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Dir:  r.dir,
+	}, ".")
+	if err != nil {
+		r.err = fmt.Errorf("can not load types of package %s: %v", r.dir, err)
+		return
+	}
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			// Package is not found or can not be listed, so it has no types.
+			if e.Kind == packages.ListError || e.Kind == packages.UnknownError {
+				r.err = fmt.Errorf("can not load types of package %s: %v", r.dir, e)
+				return
+			}
+		}
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types != nil {
+			r.packages[pkg.PkgPath] = pkg.Types
+		}
+		for _, e := range pkg.Errors {
+			fmt.Printf("Warning! types of package %s are partially known: %v\n", pkg.PkgPath, e)
+		}
+	})
+}
+
+// Returns error, when package can not be typed.
+// Templates, which depend on full type information, fail with it. Resolver may be nil.
+func (r *TypeResolver) Err() error {
+	if r == nil {
+		return nil
+	}
+	r.once.Do(r.load)
+	return r.err
+}
+
+// Returns information about type, declared in package, or nil, if it is unknown.
+// Resolver may be nil.
+func (r *TypeResolver) Lookup(pkg, name string) *NamedType {
+	if r == nil {
+		return nil
+	}
+	r.once.Do(r.load)
+	p, ok := r.packages[pkg]
+	if !ok {
+		return nil
+	}
+	obj, ok := p.Scope().Lookup(name).(*gotypes.TypeName)
+	if !ok {
+		return nil
+	}
+	named := &NamedType{Alias: obj.IsAlias()}
+	switch u := obj.Type().Underlying().(type) {
+	case *gotypes.Struct:
+		named.Kind = StructKind
+	case *gotypes.Interface:
+		named.Kind = InterfaceKind
+	case *gotypes.Basic:
+		named.Kind = BasicKind
+		named.Basic = u.Name()
+		if !named.Alias {
+			named.Values = enumValues(p, obj.Type())
+		}
+		if len(named.Values) > 0 {
+			named.Kind = EnumKind
+		}
+	case *gotypes.Signature:
+		named.Kind = FuncKind
+	case *gotypes.Chan:
+		named.Kind = ChanKind
+	default:
+		named.Kind = CompositeKind
+	}
+	return named
+}
+
+// Resolves named type, which is referenced by type of variable.
+// Not qualified names are looked up in package pkg.
+func (r *TypeResolver) Resolve(typ types.Type, pkg string) *NamedType {
+	switch f := typ.(type) {
+	case types.TImport:
+		if f.Import == nil {
+			return r.Resolve(f.Next, pkg)
+		}
+		return r.Resolve(f.Next, f.Import.Package)
+	case types.TName:
+		if util.IsInStringSlice(f.TypeName, builtinTypes) {
+			return nil
+		}
+		return r.Lookup(pkg, f.TypeName)
+	}
+	return nil
+}
+
+// Returns constants of type, declared in package, ordered as in source code.
+func enumValues(pkg *gotypes.Package, typ gotypes.Type) []EnumValue {
+	var consts []*gotypes.Const
+	for _, name := range pkg.Scope().Names() {
+		c, ok := pkg.Scope().Lookup(name).(*gotypes.Const)
+		if ok && gotypes.Identical(c.Type(), typ) {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+	values := make([]EnumValue, len(consts))
+	for i, c := range consts {
+		values[i] = EnumValue{Name: c.Name(), Value: constantValue(c.Val())}
+	}
+	return values
+}
+
+func constantValue(v constant.Value) interface{} {
+	switch v.Kind() {
+	case constant.String:
+		return constant.StringVal(v)
+	case constant.Bool:
+		return constant.BoolVal(v)
+	case constant.Int:
+		if i, ok := constant.Int64Val(v); ok {
+			return i
+		}
+		u, _ := constant.Uint64Val(v)
+		return u
+	case constant.Float:
+		f, _ := constant.Float64Val(v)
+		return f
+	}
+	return v.ExactString()
+}
+
+// Returns underlying basic type of named basic type or enum, or empty string.
+func basicType(named *NamedType) string {
+	if named != nil && (named.Kind == BasicKind || named.Kind == EnumKind) {
+		return named.Basic
+	}
+	return ""
+}
+
+// Returns error, when values of named type can not be sent through transports.
+func checkTransportable(named *NamedType, origin string) error {
+	if named == nil {
+		return nil
+	}
+	switch named.Kind {
+	case FuncKind:
+		return fmt.Errorf("%s is a function and can not be sent through transport", origin)
+	case ChanKind:
+		return fmt.Errorf("%s is a channel and can not be sent through transport", origin)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vetcher/godecl/types"
+)
+
+const typeInfoTestSource = `package stringsvc
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+)
+
+type Callback func(string)
+`
+
+func TestTypesAreResolvedFromPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"go.mod":     "module github.com/user/stringsvc\n",
+		"service.go": typeInfoTestSource,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolver := TypeResolverFor(dir)
+	level := resolver.Lookup("github.com/user/stringsvc", "Level")
+	if level == nil || level.Kind != EnumKind || level.Basic != "int" {
+		t.Fatalf("Level is resolved as %+v", level)
+	}
+	values := []EnumValue{{"Debug", int64(0)}, {"Info", int64(1)}, {"Warn", int64(2)}}
+	if !reflect.DeepEqual(level.Values, values) {
+		t.Errorf("Level values are %v, expected %v", level.Values, values)
+	}
+	if err := checkTransportable(resolver.Lookup("github.com/user/stringsvc", "Callback"), "Callback"); err == nil {
+		t.Error("function type is transportable")
+	}
+
+	levels := types.Variable{
+		Base: types.Base{Name: "levels"},
+		Type: types.TArray{IsSlice: true, Next: types.TName{TypeName: "Level"}},
+	}
+	info := protoTestInfo(t, dir, levels)
+	info.ProtobufPackage = "github.com/user/protobuf/stringsvc"
+	info.Types = resolver
+
+	out := renderTemplate(t, NewStubGRPCTypeConverterTemplate(info))
+	for _, line := range []string{
+		"func ListLevelToProto(value []stringsvc.Level) ([]int64, error) {",
+		"converted[i] = int64(elem)",
+		"converted[i] = stringsvc.Level(elem)",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("converters do not contain %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestTypeResolverErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Directory has no go files, so service package can not be typed.
+	text := types.Variable{Base: types.Base{Name: "text"}, Type: types.TName{TypeName: "string"}}
+	info := protoTestInfo(t, dir, text)
+	info.ProtobufPackage = "github.com/user/protobuf/stringsvc"
+	info.Types = TypeResolverFor(dir)
+	if info.Types.Err() == nil {
+		t.Fatal("error of loading is not returned")
+	}
+	for _, tmpl := range append(NewProtoTemplates(info), NewOpenAPITemplate(info), NewStubGRPCTypeConverterTemplate(info)) {
+		if err := tmpl.Prepare(); err == nil {
+			t.Errorf("%s: prepared without types of service package", tmpl.DefaultPath())
+		}
+	}
+}
@@ -16,6 +16,36 @@ func ValidateInterface(iface *types.Interface) error {
 	return util.ComposeErrors(errs)
 }
 
+// Services of one package, which layout places endpoints in one package (e.g. legacy layout),
+// share exchanges and endpoint constructors, so names of their methods should be different.
+// Layout may be nil, which means legacy layout.
+func ValidatePackageInterfaces(ifaces []*types.Interface, layout template.Layout) error {
+	if layout == nil {
+		layout, _ = template.LayoutByName("")
+	}
+	var errs []error
+	// Services by names of methods in every endpoint package.
+	declared := make(map[string]map[string]string)
+	for _, iface := range ifaces {
+		info := &template.GenerationInfo{Iface: iface, Layout: layout}
+		if len(ifaces) > 1 {
+			info.Namespace = util.ToSnakeCase(iface.Name)
+		}
+		dir, _ := layout.Package(info, template.EndpointPackage)
+		if declared[dir] == nil {
+			declared[dir] = make(map[string]string)
+		}
+		for _, m := range iface.Methods {
+			if other, ok := declared[dir][m.Name]; ok {
+				errs = append(errs, fmt.Errorf("%s.%s: method with the same name declared in %s", iface.Name, m.Name, other))
+				continue
+			}
+			declared[dir][m.Name] = iface.Name
+		}
+	}
+	return util.ComposeErrors(errs)
+}
+
 // Rules:
 // * First argument is context.Context.
 // * Last result is error.
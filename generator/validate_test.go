@@ -0,0 +1,42 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devimteam/microgen/generator/template"
+	"github.com/vetcher/godecl/types"
+)
+
+func TestValidatePackageInterfaces(t *testing.T) {
+	iface := func(name string, methods ...string) *types.Interface {
+		iface := &types.Interface{Base: types.Base{Name: name}}
+		for _, method := range methods {
+			iface.Methods = append(iface.Methods, &types.Function{Base: types.Base{Name: method}})
+		}
+		return iface
+	}
+
+	if err := ValidatePackageInterfaces([]*types.Interface{
+		iface("StringService", "Uppercase", "Count"),
+		iface("SizeService", "Size"),
+	}, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	duplicate := []*types.Interface{
+		iface("StringService", "Uppercase", "Count"),
+		iface("CountService", "Count"),
+	}
+	err := ValidatePackageInterfaces(duplicate, nil)
+	if err == nil || !strings.Contains(err.Error(), "CountService.Count: method with the same name declared in StringService") {
+		t.Errorf("duplicate method is not reported: %v", err)
+	}
+	// Every service has its own endpoint package.
+	addsvc, err := template.LayoutByName(template.AddsvcLayoutName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidatePackageInterfaces(duplicate, addsvc); err != nil {
+		t.Errorf("unexpected error in addsvc layout: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package write_strategy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// Beginning of header of generated files, see template.FileHeader.
+	headerPrefix = `// This file was automatically generated by "microgen`
+	// Line after header with checksum of the rest of file.
+	checksumPrefix = "// Checksum: "
+)
+
+// Adds checksum of content after generated header.
+// Content without header is returned as is.
+//
+//		// This file was automatically generated by "microgen 0.6.0" utility.
+//		// Checksum: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//		// Please, do not edit.
+//
+func addChecksum(content []byte) []byte {
+	if !bytes.HasPrefix(content, []byte(headerPrefix)) {
+		return content
+	}
+	i := bytes.IndexByte(content, '\n')
+	if i < 0 {
+		return content
+	}
+	result := append([]byte{}, content[:i+1]...)
+	result = append(result, checksumPrefix+contentChecksum(content)+"\n"...)
+	return append(result, content[i+1:]...)
+}
+
+// Reports, that generated file was changed after generation: its content does not match its checksum.
+// Files without checksum, e.g. generated by previous versions, are not modified.
+func IsModified(content []byte) bool {
+	stripped, sum, ok := splitChecksum(content)
+	return ok && contentChecksum(stripped) != sum
+}
+
+// Returns content without checksum line and checksum. Third result reports, that checksum was found.
+func splitChecksum(content []byte) ([]byte, string, bool) {
+	if !bytes.HasPrefix(content, []byte(headerPrefix)) {
+		return nil, "", false
+	}
+	i := bytes.IndexByte(content, '\n')
+	if i < 0 {
+		return nil, "", false
+	}
+	rest := content[i+1:]
+	if !bytes.HasPrefix(rest, []byte(checksumPrefix)) {
+		return nil, "", false
+	}
+	j := bytes.IndexByte(rest, '\n')
+	if j < 0 {
+		return nil, "", false
+	}
+	stripped := append(append([]byte{}, content[:i+1]...), rest[j+1:]...)
+	return stripped, string(rest[len(checksumPrefix):j]), true
+}
+
+func contentChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
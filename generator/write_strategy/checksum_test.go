@@ -0,0 +1,28 @@
+package write_strategy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	content := []byte("// This file was automatically generated by \"microgen 0.6.0\" utility.\n// Please, do not edit.\npackage svc\n")
+	generated := addChecksum(content)
+	lines := strings.Split(string(generated), "\n")
+	if !strings.HasPrefix(lines[1], checksumPrefix) || lines[2] != "// Please, do not edit." {
+		t.Fatalf("checksum is not placed after header:\n%s", generated)
+	}
+	if IsModified(generated) {
+		t.Errorf("generated file is modified:\n%s", generated)
+	}
+	if !IsModified([]byte(strings.Replace(string(generated), "package svc", "package svc\n\nvar x = 1", 1))) {
+		t.Errorf("edited file is not modified")
+	}
+	// Files without checksum are generated by previous versions.
+	if IsModified(content) {
+		t.Errorf("file without checksum is modified")
+	}
+	if string(addChecksum([]byte("package svc\n"))) != "package svc\n" {
+		t.Errorf("checksum is added to file without header")
+	}
+}
@@ -1,11 +1,47 @@
 package write_strategy
 
-import "io"
+import (
+	"bytes"
+	"io"
+)
+
+const (
+	NewFileMark    = "New"
+	AppendFileMark = "Add"
+	MergeFileMark  = "Merge"
+	RemoveFileMark = "Remove"
+	SkipFileMark   = "Skip"
+)
+
+// Marks functions, which are generated for interface, e.g. `//microgen:generated StringService`.
+// Merge strategy comments out marked functions of interface, which are not rendered anymore.
+const GeneratedMarker = "//microgen:generated"
 
 type Renderer interface {
 	Render(io.Writer) error
 }
 
+// Strategy decides, what is done with file. Plans are written by Transaction.
 type Strategy interface {
-	Write(Renderer) error
+	// Returns what should be done with file, without touching file system.
+	Plan(Renderer) (*Plan, error)
+}
+
+// Plan describes what strategy does with file.
+type Plan struct {
+	// One of NewFileMark, AppendFileMark, MergeFileMark, RemoveFileMark or SkipFileMark.
+	Action string
+	// Path to file.
+	Path string
+	// Content of file before writing, nil if file does not exist.
+	Current []byte
+	// Content of file after writing, nil if file is removed.
+	Result []byte
+	// Applies strategy to other content of file, used to combine plans of one file.
+	reapply func(current []byte) ([]byte, error)
+}
+
+// Reports, that file content after writing differs from file on disk.
+func (p *Plan) Changed() bool {
+	return !bytes.Equal(p.Current, p.Result)
 }
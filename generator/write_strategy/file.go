@@ -6,7 +6,6 @@ import (
 	"go/format"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 )
 
@@ -17,60 +16,45 @@ const (
 	// It makes formatter think, that declared code is top-level.
 	// Without this hack formatter adds separators (tabs) to beginning of every line.
 	formatTrick = "package T\n"
-
-	NewFileMark    = "New"
-	AppendFileMark = "Add"
 )
 
 type createFileStrategy struct {
 	absPath string
 	relPath string
+	// File is not go source, so it is written without formatting.
+	raw bool
 }
 
-func (s createFileStrategy) Write(renderer Renderer) error {
-	outpath, err := filepath.Abs(filepath.Join(s.absPath, s.relPath))
+func (s createFileStrategy) Plan(f Renderer) (*Plan, error) {
+	formatted, err := s.render(f)
 	if err != nil {
-		return fmt.Errorf("unable to resolve path: %v", err)
+		return nil, err
 	}
-	dir := path.Dir(outpath)
-
-	_, err = os.Stat(dir)
-
-	if os.IsNotExist(err) {
-		err = os.MkdirAll(dir, MkdirPermissions)
-		if err != nil {
-			return fmt.Errorf("unable to create directory %s: %v", outpath, err)
+	return newPlan(s.absPath, s.relPath, func(current []byte) ([]byte, string) {
+		if formatted == nil {
+			return current, SkipFileMark
 		}
-	} else if err != nil {
-		return fmt.Errorf("could not stat file: %v", err)
-	}
-
-	err = s.Save(renderer, outpath)
-	if err != nil {
-		return fmt.Errorf("error when save file: %v", err)
-	}
-	return nil
+		return formatted, NewFileMark
+	})
 }
 
-// Copied from original github.com/dave/jennifer/jen.go func Save()
-func (s createFileStrategy) Save(f Renderer, filename string) error {
+// Returns formatted code with checksum in header or nil, if renderer produced nothing.
+func (s createFileStrategy) render(f Renderer) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	if err := f.Render(buf); err != nil {
-		return err
+		return nil, err
 	}
-	// Stop saving because nothing
 	if len(buf.Bytes()) == 0 {
-		return nil
+		return nil, nil
+	}
+	if s.raw {
+		return buf.Bytes(), nil
 	}
 	formatted, err := format.Source(buf.Bytes())
 	if err != nil {
-		return fmt.Errorf("error when format source: %v", err)
-	}
-	if err := ioutil.WriteFile(filename, formatted, 0644); err != nil {
-		return err
+		return nil, fmt.Errorf("error when format source: %v", err)
 	}
-	fmt.Println(NewFileMark, filepath.Join(s.absPath, s.relPath))
-	return nil
+	return addChecksum(formatted), nil
 }
 
 func NewCreateFileStrategy(absPath, relPath string) Strategy {
@@ -80,6 +64,15 @@ func NewCreateFileStrategy(absPath, relPath string) Strategy {
 	}
 }
 
+// Creates strategy, which writes files without go formatting, e.g. .proto files.
+func NewCreateRawFileStrategy(absPath, relPath string) Strategy {
+	return createFileStrategy{
+		absPath: absPath,
+		relPath: relPath,
+		raw:     true,
+	}
+}
+
 type appendFileStrategy struct {
 	absPath string
 	relPath string
@@ -92,62 +85,51 @@ func NewAppendToFileStrategy(absPath, relPath string) Strategy {
 	}
 }
 
-func (s appendFileStrategy) Write(renderer Renderer) error {
-	outpath, err := filepath.Abs(filepath.Join(s.absPath, s.relPath))
+func (s appendFileStrategy) Plan(renderer Renderer) (*Plan, error) {
+	formatted, err := s.render(renderer)
 	if err != nil {
-		return fmt.Errorf("unable to resolve path: %v", err)
+		return nil, err
 	}
-	dir := path.Dir(outpath)
-
-	_, err = os.Stat(dir)
-
-	if os.IsNotExist(err) {
-		err = os.MkdirAll(dir, MkdirPermissions)
-		if err != nil {
-			return fmt.Errorf("unable to create directory %s: %v", outpath, err)
+	return newPlan(s.absPath, s.relPath, func(current []byte) ([]byte, string) {
+		if formatted == nil {
+			return current, SkipFileMark
 		}
-	} else if err != nil {
-		return fmt.Errorf("could not stat file: %v", err)
-	}
-
-	if _, err = os.Stat(outpath); os.IsNotExist(err) {
-		f, err := os.Create(outpath)
-		if err != nil {
-			return fmt.Errorf("can't create %s: error: %v", outpath, err)
-		}
-		f.Close()
-	}
-
-	err = s.Save(renderer, outpath)
-	if err != nil {
-		return fmt.Errorf("error when save file: %v", err)
-	}
-	return nil
+		return append(append([]byte{}, current...), formatted...), AppendFileMark
+	})
 }
 
-func (s appendFileStrategy) Save(renderer Renderer, filename string) error {
+// Returns formatted top-level code or nil, if renderer produced nothing.
+func (s appendFileStrategy) render(renderer Renderer) ([]byte, error) {
 	buf := &bytes.Buffer{}
 	if err := renderer.Render(buf); err != nil {
-		return err
+		return nil, err
 	}
-
-	// Stop saving because nothing
 	if len(buf.Bytes()) == 0 {
-		return nil
+		return nil, nil
 	}
 	// Use trick for top-level formatting.
 	formatted, err := format.Source(append([]byte(formatTrick), buf.Bytes()...))
 	if err != nil {
-		return fmt.Errorf("error when format source: %v", err)
+		return nil, fmt.Errorf("error when format source: %v", err)
 	}
+	return formatted[len(formatTrick):], nil
+}
 
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+// Reads current content of file and builds plan with result of apply.
+func newPlan(absPath, relPath string, apply func(current []byte) (result []byte, action string)) (*Plan, error) {
+	outpath, err := filepath.Abs(filepath.Join(absPath, relPath))
 	if err != nil {
-		return err
-	}
-	if _, err = f.Write(formatted[len(formatTrick):]); err != nil {
-		return err
-	}
-	fmt.Println(AppendFileMark, filepath.Join(s.absPath, s.relPath))
-	return nil
+		return nil, fmt.Errorf("unable to resolve path: %v", err)
+	}
+	current, err := ioutil.ReadFile(outpath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	result, action := apply(current)
+	return &Plan{
+		Action:  action,
+		Path:    filepath.Join(absPath, relPath),
+		Current: current,
+		Result:  result,
+	}, nil
 }
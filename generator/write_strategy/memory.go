@@ -0,0 +1,63 @@
+package write_strategy
+
+import (
+	"sort"
+	"sync"
+)
+
+// Memory keeps results of strategies in memory instead of writing them to file system.
+type Memory struct {
+	mx    sync.Mutex
+	plans []*Plan
+}
+
+type memoryStrategy struct {
+	memory   *Memory
+	strategy Strategy
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Returns strategy, which saves plans of provided strategy to memory.
+func (m *Memory) Wrap(s Strategy) Strategy {
+	return memoryStrategy{
+		memory:   m,
+		strategy: s,
+	}
+}
+
+// Returns all saved plans, sorted by path.
+func (m *Memory) Plans() []*Plan {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	plans := append([]*Plan{}, m.plans...)
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].Path < plans[j].Path
+	})
+	return plans
+}
+
+// Returns plans, which results differ from files on disk.
+// Append-only and merged files differ only when some declarations are missing or outdated.
+func (m *Memory) Changed() []*Plan {
+	var changed []*Plan
+	for _, plan := range m.Plans() {
+		if plan.Changed() {
+			changed = append(changed, plan)
+		}
+	}
+	return changed
+}
+
+func (s memoryStrategy) Plan(renderer Renderer) (*Plan, error) {
+	plan, err := s.strategy.Plan(renderer)
+	if err != nil {
+		return nil, err
+	}
+	s.memory.mx.Lock()
+	s.memory.plans = append(s.memory.plans, plan)
+	s.memory.mx.Unlock()
+	return plan, nil
+}
@@ -0,0 +1,54 @@
+package write_strategy
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type stringRenderer string
+
+func (s stringRenderer) Render(w io.Writer) error {
+	_, err := io.WriteString(w, string(s))
+	return err
+}
+
+func TestMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "service.go"), []byte("package svc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	memory := NewMemory()
+	for _, c := range []struct {
+		strategy Strategy
+		code     string
+	}{
+		// Nothing is missing in append-only file.
+		{NewAppendToFileStrategy(dir, "service.go"), ""},
+		{NewAppendToFileStrategy(dir, "stub.go"), "func Count() {}"},
+		{NewCreateFileStrategy(dir, "endpoints.go"), "package svc"},
+	} {
+		if _, err := memory.Wrap(c.strategy).Plan(stringRenderer(c.code)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("memory writes files: %d files in directory", len(files))
+	}
+
+	plans := memory.Plans()
+	if len(plans) != 3 || plans[0].Path != filepath.Join(dir, "endpoints.go") || plans[2].Path != filepath.Join(dir, "stub.go") {
+		t.Fatalf("plans are not sorted by path: %v", plans)
+	}
+	changed := memory.Changed()
+	if len(changed) != 2 || changed[0].Action != NewFileMark || changed[1].Action != AppendFileMark {
+		t.Errorf("unexpected changed plans: %v", changed)
+	}
+}
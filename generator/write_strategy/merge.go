@@ -0,0 +1,537 @@
+package write_strategy
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+type mergeFileStrategy struct {
+	absPath string
+	relPath string
+	// Bodies of marked declarations, which were not changed after generation, are replaced.
+	refresh bool
+}
+
+// Creates strategy, which merges rendered declarations into existing file.
+// Missing declarations are appended. Signatures of existing functions and methods are replaced,
+// when types of their parameters or results differ from rendered ones, their receivers are kept.
+// Bodies of marked declarations are replaced, when they were not changed after generation:
+// marker holds checksum of generated body. Changed bodies are kept.
+// Other existing declarations and declarations of user are not changed.
+// Imports of rendered file are added, imports, which are not used after merge, are removed.
+// When file does not exist, rendered file is written.
+func NewMergeFileStrategy(absPath, relPath string) Strategy {
+	return mergeFileStrategy{
+		absPath: absPath,
+		relPath: relPath,
+		refresh: true,
+	}
+}
+
+// Creates merge strategy, which always keeps bodies of existing declarations,
+// e.g. for stub of service, which methods are written by user.
+func NewMergeFileKeepBodiesStrategy(absPath, relPath string) Strategy {
+	return mergeFileStrategy{
+		absPath: absPath,
+		relPath: relPath,
+	}
+}
+
+func (s mergeFileStrategy) Plan(renderer Renderer) (*Plan, error) {
+	buf := &bytes.Buffer{}
+	if err := renderer.Render(buf); err != nil {
+		return nil, err
+	}
+	rendered := buf.Bytes()
+	var mergeErr error
+	plan, err := newPlan(s.absPath, s.relPath, func(current []byte) ([]byte, string) {
+		if len(rendered) == 0 {
+			return current, SkipFileMark
+		}
+		if current == nil {
+			marked, err := s.mark(rendered)
+			if err != nil {
+				mergeErr = err
+				return nil, NewFileMark
+			}
+			formatted, err := format.Source(marked)
+			if err != nil {
+				mergeErr = fmt.Errorf("error when format source: %v", err)
+				return nil, NewFileMark
+			}
+			return formatted, NewFileMark
+		}
+		result, err := mergeSource(current, rendered, s.refresh)
+		if err != nil {
+			mergeErr = err
+			return current, SkipFileMark
+		}
+		if bytes.Equal(result, current) {
+			return current, SkipFileMark
+		}
+		return result, MergeFileMark
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mergeErr != nil {
+		return nil, mergeErr
+	}
+	if len(rendered) > 0 {
+		plan.reapply = func(current []byte) ([]byte, error) {
+			return mergeSource(current, rendered, s.refresh)
+		}
+	}
+	return plan, nil
+}
+
+// Adds checksums of bodies to markers of rendered declarations, when bodies are refreshed.
+func (s mergeFileStrategy) mark(rendered []byte) ([]byte, error) {
+	if !s.refresh {
+		return rendered, nil
+	}
+	return markChecksums(rendered)
+}
+
+// Replacement of bytes from..to of source.
+type sourceEdit struct {
+	from, to int
+	text     []byte
+}
+
+// Merges rendered declarations into current source.
+// Rendered source may contain only declarations without package clause.
+// When refresh is true, bodies of marked declarations, which match checksums of their markers, are replaced.
+// Current source is returned as is, when there is nothing to merge.
+func mergeSource(current, rendered []byte, refresh bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", current, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse existing file: %v", err)
+	}
+	if !hasPackageClause(rendered) {
+		rendered = append([]byte(formatTrick), rendered...)
+	}
+	if refresh {
+		if rendered, err = markChecksums(rendered); err != nil {
+			return nil, err
+		}
+	}
+	genFset := token.NewFileSet()
+	gen, err := parser.ParseFile(genFset, "", rendered, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rendered code: %v", err)
+	}
+	source := func(from, to token.Pos) []byte {
+		return rendered[genFset.Position(from).Offset:genFset.Position(to).Offset]
+	}
+
+	existing := declaredNames(file)
+	var (
+		edits    []sourceEdit
+		appended [][]byte
+		// Names of rendered functions and owners of marked ones.
+		generated = make(map[string]bool)
+		owners    = make(map[string]bool)
+	)
+	// Replaces body of existing marked declaration and its marker, when body was not changed after generation.
+	// Marker without checksum gets it, when body is the same as rendered one.
+	refreshBody := func(old, decl ast.Decl) {
+		marker, owner, sum := findMarker(declDoc(decl))
+		oldMarker, _, oldSum := findMarker(declDoc(old))
+		if !refresh || marker == nil || oldMarker == nil || oldSum == sum {
+			return
+		}
+		from, to := bodyRange(old)
+		oldBody := current[fset.Position(from).Offset:fset.Position(to).Offset]
+		switch bodyChecksum(oldBody) {
+		case oldSum:
+			genFrom, genTo := bodyRange(decl)
+			edits = append(edits, sourceEdit{
+				from: fset.Position(from).Offset,
+				to:   fset.Position(to).Offset,
+				text: source(genFrom, genTo),
+			})
+		case sum:
+		default:
+			return
+		}
+		edits = append(edits, sourceEdit{
+			from: fset.Position(oldMarker.Pos()).Offset,
+			to:   fset.Position(oldMarker.End()).Offset,
+			text: []byte(markerText(owner, sum)),
+		})
+	}
+	for _, decl := range gen.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			generated[funcDeclName(d)] = true
+			marker, owner, _ := findMarker(d.Doc)
+			if marker != nil {
+				owners[owner] = true
+			}
+			old, ok := existing[funcDeclName(d)].(*ast.FuncDecl)
+			if !ok {
+				appended = append(appended, source(declPos(d), d.End()))
+				continue
+			}
+			if oldMarker, _, _ := findMarker(old.Doc); marker != nil && oldMarker == nil {
+				// Functions of previous versions are marked, so they are found, when they become stale.
+				// Their bodies may be changed by user, so checksum is not added.
+				edits = append(edits, sourceEdit{
+					from: fset.Position(old.Pos()).Offset,
+					to:   fset.Position(old.Pos()).Offset,
+					text: []byte(markerText(owner, "") + "\n"),
+				})
+			}
+			refreshBody(old, d)
+			if signature(old.Type) == signature(d.Type) {
+				continue
+			}
+			// Receiver and name are kept, so body of function is still valid.
+			edits = append(edits, sourceEdit{
+				from: fset.Position(old.Type.Params.Pos()).Offset,
+				to:   fset.Position(old.Type.End()).Offset,
+				text: source(d.Type.Params.Pos(), d.Type.End()),
+			})
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			if isDeclared(existing, d) {
+				if old, ok := existing[genDeclNames(d)[0]].(*ast.GenDecl); ok {
+					refreshBody(old, d)
+				}
+				continue
+			}
+			appended = append(appended, source(declPos(d), d.End()))
+		}
+	}
+	// Marked functions of the same interfaces, which are not rendered anymore, are stale,
+	// e.g. converters of removed method. They are commented out, because user may have changed them.
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || generated[funcDeclName(fn)] {
+			continue
+		}
+		if marker, owner, _ := findMarker(fn.Doc); marker == nil || !owners[owner] {
+			continue
+		}
+		from, to := fset.Position(declPos(fn)).Offset, fset.Position(fn.End()).Offset
+		edits = append(edits, sourceEdit{
+			from: from,
+			to:   to,
+			text: commentOut(funcDeclName(fn), current[from:to]),
+		})
+	}
+	if len(edits) == 0 && len(appended) == 0 {
+		return current, nil
+	}
+
+	result := append([]byte{}, current...)
+	sort.Slice(edits, func(i, j int) bool { return edits[i].from > edits[j].from })
+	for _, e := range edits {
+		result = append(result[:e.from], append(append([]byte{}, e.text...), result[e.to:]...)...)
+	}
+	for _, decl := range appended {
+		result = append(bytes.TrimRight(result, "\n"), "\n\n"...)
+		result = append(result, decl...)
+		result = append(result, '\n')
+	}
+	return fixImports(result, gen, usedPackages(file))
+}
+
+// Adds imports of rendered file and removes imports, which are not used anymore.
+// Only imports, which were used before merge, are removed, because name of imported
+// package is guessed from its path.
+func fixImports(source []byte, gen *ast.File, usedBefore map[string]bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse merged file: %v", err)
+	}
+	for _, spec := range gen.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		astutil.AddNamedImport(fset, file, name, importPath)
+	}
+	used := usedPackages(file)
+	var unused []*ast.ImportSpec
+	for _, spec := range file.Imports {
+		if name := importName(spec); name != "" && !used[name] && usedBefore[name] {
+			unused = append(unused, spec)
+		}
+	}
+	for _, spec := range unused {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			astutil.DeleteNamedImport(fset, file, spec.Name.Name, importPath)
+		} else {
+			astutil.DeleteImport(fset, file, importPath)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := format.Node(buf, fset, file); err != nil {
+		return nil, fmt.Errorf("error when format source: %v", err)
+	}
+	return format.Source(buf.Bytes())
+}
+
+// Returns top-level declarations by names. Methods are named as `Receiver.Method`.
+func declaredNames(file *ast.File) map[string]ast.Decl {
+	names := make(map[string]ast.Decl)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			names[funcDeclName(d)] = d
+		case *ast.GenDecl:
+			for _, name := range genDeclNames(d) {
+				names[name] = d
+			}
+		}
+	}
+	return names
+}
+
+// Reports, that all names of declaration are already declared.
+func isDeclared(existing map[string]ast.Decl, decl *ast.GenDecl) bool {
+	names := genDeclNames(decl)
+	for _, name := range names {
+		if _, ok := existing[name]; !ok {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+func genDeclNames(decl *ast.GenDecl) (names []string) {
+	for _, spec := range decl.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				if name.Name != "_" {
+					names = append(names, name.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// Returns name of function or `Receiver.Method` for methods.
+func funcDeclName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	typ := decl.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+			continue
+		case *ast.IndexExpr:
+			typ = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + decl.Name.Name
+		}
+		return types.ExprString(typ) + "." + decl.Name.Name
+	}
+}
+
+// Returns types of parameters and results of function, names are ignored.
+//
+//		context.Context,string|int,error
+//
+func signature(fn *ast.FuncType) string {
+	list := func(fields *ast.FieldList) string {
+		if fields == nil {
+			return ""
+		}
+		var typs []string
+		for _, field := range fields.List {
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				typs = append(typs, types.ExprString(field.Type))
+			}
+		}
+		return strings.Join(typs, ",")
+	}
+	return list(fn.Params) + "|" + list(fn.Results)
+}
+
+// Returns generated marker in doc, owner of declaration, e.g. `StringService`, and checksum
+// of generated body, which is empty for markers of previous versions. Marker is nil, when doc has no marker.
+//
+//		//microgen:generated StringService 5d41402abc4b2a76
+//
+func findMarker(doc *ast.CommentGroup) (marker *ast.Comment, owner, sum string) {
+	if doc == nil {
+		return nil, "", ""
+	}
+	for _, c := range doc.List {
+		if c.Text == GeneratedMarker || strings.HasPrefix(c.Text, GeneratedMarker+" ") {
+			fields := strings.Fields(strings.TrimPrefix(c.Text, GeneratedMarker))
+			if len(fields) > 0 {
+				owner = fields[0]
+			}
+			if len(fields) > 1 {
+				sum = fields[1]
+			}
+			return c, owner, sum
+		}
+	}
+	return nil, "", ""
+}
+
+func markerText(owner, sum string) string {
+	if sum == "" {
+		return GeneratedMarker + " " + owner
+	}
+	return GeneratedMarker + " " + owner + " " + sum
+}
+
+// Adds checksums of bodies to markers of declarations in source, which have no checksum.
+func markChecksums(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rendered code: %v", err)
+	}
+	var edits []sourceEdit
+	for _, decl := range file.Decls {
+		marker, owner, sum := findMarker(declDoc(decl))
+		if marker == nil || sum != "" {
+			continue
+		}
+		from, to := bodyRange(decl)
+		body := src[fset.Position(from).Offset:fset.Position(to).Offset]
+		edits = append(edits, sourceEdit{
+			from: fset.Position(marker.Pos()).Offset,
+			to:   fset.Position(marker.End()).Offset,
+			text: []byte(markerText(owner, bodyChecksum(body))),
+		})
+	}
+	result := append([]byte{}, src...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		result = append(result[:e.from], append(append([]byte{}, e.text...), result[e.to:]...)...)
+	}
+	return result, nil
+}
+
+// Returns checksum of body, which does not depend on formatting.
+func bodyChecksum(body []byte) string {
+	return contentChecksum([]byte(strings.Join(strings.Fields(string(body)), " ")))[:16]
+}
+
+// Returns range of body of function or of whole declaration without doc.
+func bodyRange(decl ast.Decl) (token.Pos, token.Pos) {
+	if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+		return fn.Body.Pos(), fn.Body.End()
+	}
+	return decl.Pos(), decl.End()
+}
+
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// Comments out stale function with warning. Marker is dropped, so commented function is not
+// recognized as generated.
+//
+//		// microgen: Count is not generated anymore, remove it or move it to another file.
+//		// func (s *stringService) Count(ctx context.Context) error {
+//		// 	panic("method not provided")
+//		// }
+//
+func commentOut(name string, text []byte) []byte {
+	lines := []string{"// microgen: " + name + " is not generated anymore, remove it or move it to another file."}
+	for _, line := range strings.Split(string(text), "\n") {
+		switch {
+		case strings.HasPrefix(line, GeneratedMarker):
+		case line == "":
+			lines = append(lines, "//")
+		default:
+			lines = append(lines, "// "+line)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Position of declaration with its doc comment.
+func declPos(decl ast.Decl) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
+
+// Returns names of packages, which are used in selectors, e.g. `context` in `context.Context`.
+func usedPackages(file *ast.File) map[string]bool {
+	used := make(map[string]bool)
+	ast.Inspect(file, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// Returns name, which refers to imported package, or empty string for blank
+// and dot imports and packages, which names can not be guessed from path.
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		if spec.Name.Name == "_" || spec.Name.Name == "." {
+			return ""
+		}
+		return spec.Name.Name
+	}
+	importPath, _ := strconv.Unquote(spec.Path.Value)
+	name := path.Base(importPath)
+	if !token.IsIdentifier(name) {
+		return ""
+	}
+	return name
+}
+
+func hasPackageClause(source []byte) bool {
+	fset := token.NewFileSet()
+	_, err := parser.ParseFile(fset, "", source, parser.PackageClauseOnly)
+	return err == nil
+}
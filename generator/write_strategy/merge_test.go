@@ -0,0 +1,225 @@
+package write_strategy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const mergeTestCurrent = `package stringsvc
+
+import (
+	"context"
+	"strings"
+)
+
+type StringService interface {
+	Count(ctx context.Context, text string, symbol string) (count int, err error)
+}
+
+type stringService struct {
+	limit int
+}
+
+// Count counts symbols.
+func (s *stringService) Count(ctx context.Context, text []byte) (count int, err error) {
+	// Written by user.
+	return strings.Count(string(text), "a"), nil
+}
+
+func helper() {}
+`
+
+const mergeTestRendered = `
+type stringService struct {
+}
+
+func (s *stringService) Count(ctx context.Context, text string, symbol string) (count int, err error) {
+	panic("method not provided")
+}
+
+func (s *stringService) Uppercase(ctx context.Context, text string) (ans string, err error) {
+	panic("method not provided")
+}
+`
+
+func TestMergeSource(t *testing.T) {
+	out, err := mergeSource([]byte(mergeTestCurrent), []byte(mergeTestRendered), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(out)
+	for _, line := range []string{
+		"\tlimit int\n",
+		"// Count counts symbols.\nfunc (s *stringService) Count(ctx context.Context, text string, symbol string) (count int, err error) {",
+		"\t// Written by user.\n\treturn strings.Count(string(text), \"a\"), nil",
+		"func helper() {}",
+		"func (s *stringService) Uppercase(ctx context.Context, text string) (ans string, err error) {",
+	} {
+		if !strings.Contains(result, line) {
+			t.Errorf("merged file does not contain %q:\n%s", line, result)
+		}
+	}
+	if strings.Count(result, "type stringService struct") != 1 {
+		t.Errorf("structure is duplicated:\n%s", result)
+	}
+
+	again, err := mergeSource(out, []byte(mergeTestRendered), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != result {
+		t.Errorf("second merge changed file:\n%s", again)
+	}
+}
+
+func TestMergeSourceImports(t *testing.T) {
+	current := `package protobuf
+
+import (
+	"fmt"
+	"time"
+)
+
+func DurationToProto(value time.Duration) (int64, error) {
+	return int64(value), nil
+}
+
+func debug() { fmt.Println() }
+`
+	rendered := `package protobuf
+
+import duration "github.com/golang/protobuf/ptypes/duration"
+
+func DurationToProto(value int64) (*duration.Duration, error) {
+	panic("function not provided")
+}
+`
+	out, err := mergeSource([]byte(current), []byte(rendered), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(out)
+	if !strings.Contains(result, `duration "github.com/golang/protobuf/ptypes/duration"`) {
+		t.Errorf("import is not added:\n%s", result)
+	}
+	if strings.Contains(result, `"time"`) {
+		t.Errorf("unused import is not removed:\n%s", result)
+	}
+	if !strings.Contains(result, `"fmt"`) {
+		t.Errorf("used import is removed:\n%s", result)
+	}
+	if !strings.Contains(result, "return int64(value), nil") {
+		t.Errorf("body is not kept:\n%s", result)
+	}
+}
+
+func TestMergeSourceStale(t *testing.T) {
+	current := `package protobuf
+
+//microgen:generated StringService
+func EncodeCountRequest(request interface{}) (interface{}, error) {
+	return request, nil
+}
+
+//microgen:generated StringService
+func EncodeSizeRequest(request interface{}) (interface{}, error) {
+
+	return request, nil
+}
+
+//microgen:generated UserService
+func EncodeGetRequest(request interface{}) (interface{}, error) {
+	return request, nil
+}
+
+func EncodeCustomRequest(request interface{}) (interface{}, error) {
+	return request, nil
+}
+`
+	rendered := `package protobuf
+
+//microgen:generated StringService
+func EncodeCountRequest(request interface{}) (interface{}, error) {
+	panic("function not provided")
+}
+`
+	out, err := mergeSource([]byte(current), []byte(rendered), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(out)
+	for _, line := range []string{
+		"// microgen: EncodeSizeRequest is not generated anymore, remove it or move it to another file.\n" +
+			"// func EncodeSizeRequest(request interface{}) (interface{}, error) {\n//\n// \treturn request, nil\n// }",
+		"\nfunc EncodeCountRequest(request interface{}) (interface{}, error) {\n\treturn request, nil",
+		"\nfunc EncodeGetRequest(",
+		"\nfunc EncodeCustomRequest(",
+	} {
+		if !strings.Contains(result, line) {
+			t.Errorf("merged file does not contain %q:\n%s", line, result)
+		}
+	}
+
+	again, err := mergeSource(out, []byte(rendered), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != result {
+		t.Errorf("second merge changed file:\n%s", again)
+	}
+}
+
+func TestMergeSourceRefresh(t *testing.T) {
+	v1 := `package protobuf
+
+//microgen:generated StringService
+func EncodeCountRequest(request interface{}) (interface{}, error) {
+	return request, nil
+}
+
+//microgen:generated StringService
+func EncodeSizeRequest(request interface{}) (interface{}, error) {
+	return request, nil
+}
+`
+	v2 := strings.Replace(v1, "return request, nil", "return &request, nil", -1)
+	current, err := markChecksums([]byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Body of size encoder is changed by user.
+	current = bytes.Replace(current, []byte("EncodeSizeRequest(request interface{}) (interface{}, error) {\n\treturn request"), []byte("EncodeSizeRequest(request interface{}) (interface{}, error) {\n\treturn nil"), 1)
+	current = bytes.Replace(current, []byte("EncodeCountRequest(request interface{}) (interface{}, error) {\n\treturn request"), []byte("EncodeCountRequest(request interface{}) (interface{}, error) {\n\n\treturn  request"), 1)
+
+	out, err := mergeSource(current, []byte(v2), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(out)
+	for _, line := range []string{
+		// Formatting is not a change.
+		"func EncodeCountRequest(request interface{}) (interface{}, error) {\n\treturn &request, nil",
+		"func EncodeSizeRequest(request interface{}) (interface{}, error) {\n\treturn nil, nil",
+	} {
+		if !strings.Contains(result, line) {
+			t.Errorf("merged file does not contain %q:\n%s", line, result)
+		}
+	}
+	// Checksum of refreshed body is updated, checksum of changed body is kept.
+	if strings.Count(result, bodyChecksum([]byte("{ return &request, nil }"))) != 1 {
+		t.Errorf("checksum of refreshed body is not updated:\n%s", result)
+	}
+
+	again, err := mergeSource(out, []byte(v2), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != result {
+		t.Errorf("second merge changed file:\n%s", again)
+	}
+	// Bodies are kept, when they are not refreshed.
+	if kept, err := mergeSource(current, []byte(v2), false); err != nil || strings.Contains(string(kept), "&request") {
+		t.Errorf("body is refreshed: %v\n%s", err, kept)
+	}
+}
@@ -13,10 +13,8 @@ func NewNopStrategy(absPath, relPath string) Strategy {
 	}
 }
 
-func (s nopStrategy) Write(Renderer) error {
-	return nil
-}
-
-func (s nopStrategy) Save(Renderer, string) error {
-	return nil
+func (s nopStrategy) Plan(Renderer) (*Plan, error) {
+	return newPlan(s.absPath, s.relPath, func(current []byte) ([]byte, string) {
+		return current, SkipFileMark
+	})
 }
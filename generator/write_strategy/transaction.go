@@ -0,0 +1,226 @@
+package write_strategy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/devimteam/microgen/util"
+)
+
+// Transaction stages planned files in temporary directory and writes all of them at once.
+// When writing of some file fails, all already written files are restored.
+type Transaction struct {
+	dir    string
+	staged []*stagedFile
+}
+
+type stagedFile struct {
+	plan   *Plan
+	path   string
+	tmp    string
+	backup string
+	// Directories, which were created for file.
+	createdDirs []string
+	committed   bool
+}
+
+// Creates transaction with new temporary directory.
+// Close should be called to remove this directory.
+func NewTransaction() (*Transaction, error) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory: %v", err)
+	}
+	return &Transaction{dir: dir}, nil
+}
+
+// Writes result of plan to temporary directory.
+// Plans without changes are ignored.
+func (t *Transaction) Stage(plan *Plan) error {
+	if !plan.Changed() {
+		return nil
+	}
+	path, err := filepath.Abs(plan.Path)
+	if err != nil {
+		return fmt.Errorf("unable to resolve path: %v", err)
+	}
+	for _, f := range t.staged {
+		if f.path != path {
+			continue
+		}
+		// Same file may be produced by different templates, e.g. converters for http-client and http-server tags.
+		if bytes.Equal(f.plan.Result, plan.Result) {
+			return nil
+		}
+		// Several templates may append to the same file, e.g. stubs of services, declared in one file.
+		if f.plan.Action == AppendFileMark && plan.Action == AppendFileMark && bytes.Equal(f.plan.Current, plan.Current) {
+			combined := *f.plan
+			combined.Result = append(append([]byte{}, f.plan.Result...), plan.Result[len(plan.Current):]...)
+			if err := ioutil.WriteFile(f.tmp, combined.Result, 0644); err != nil {
+				return fmt.Errorf("unable to stage %s: %v", plan.Path, err)
+			}
+			f.plan = &combined
+			return nil
+		}
+		// Several templates may merge declarations into the same file.
+		if f.plan.Action == MergeFileMark && plan.reapply != nil && bytes.Equal(f.plan.Current, plan.Current) {
+			combined := *f.plan
+			result, err := plan.reapply(f.plan.Result)
+			if err != nil {
+				return fmt.Errorf("%s: %v", plan.Path, err)
+			}
+			combined.Result = result
+			if err := ioutil.WriteFile(f.tmp, combined.Result, 0644); err != nil {
+				return fmt.Errorf("unable to stage %s: %v", plan.Path, err)
+			}
+			f.plan = &combined
+			return nil
+		}
+		return fmt.Errorf("%s: conflicting results of generation", plan.Path)
+	}
+	tmp := filepath.Join(t.dir, strconv.Itoa(len(t.staged)))
+	// Removed files have nothing to stage.
+	if plan.Action != RemoveFileMark {
+		if err := ioutil.WriteFile(tmp, plan.Result, 0644); err != nil {
+			return fmt.Errorf("unable to stage %s: %v", plan.Path, err)
+		}
+	}
+	t.staged = append(t.staged, &stagedFile{
+		plan: plan,
+		path: path,
+		tmp:  tmp,
+	})
+	return nil
+}
+
+// Returns content of staged file after commit. Plans of one file may be combined,
+// so content may differ from result of every plan.
+func (t *Transaction) Staged(path string) ([]byte, bool) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false
+	}
+	for _, f := range t.staged {
+		if f.path == path {
+			return f.plan.Result, true
+		}
+	}
+	return nil, false
+}
+
+// Moves all staged files to their destinations, backing up original files.
+// Removed files are moved to backups.
+// If some file can not be written, original files are restored and new files are removed.
+func (t *Transaction) Commit() error {
+	for _, f := range t.staged {
+		if err := t.commit(f); err != nil {
+			if rollbackErr := t.rollback(); rollbackErr != nil {
+				return fmt.Errorf("%s: %v, rollback error: %v", f.plan.Path, err, rollbackErr)
+			}
+			return fmt.Errorf("%s: %v, all changes were rolled back", f.plan.Path, err)
+		}
+	}
+	for _, f := range t.staged {
+		fmt.Println(f.plan.Action, f.plan.Path)
+	}
+	return nil
+}
+
+// Removes temporary directory with staged files and backups.
+func (t *Transaction) Close() error {
+	return os.RemoveAll(t.dir)
+}
+
+func (t *Transaction) commit(f *stagedFile) error {
+	if f.plan.Action == RemoveFileMark {
+		f.backup = f.tmp + ".orig"
+		if err := moveFile(f.path, f.backup); err != nil {
+			f.backup = ""
+			return fmt.Errorf("unable to remove file: %v", err)
+		}
+		f.committed = true
+		return nil
+	}
+	dirs, err := mkdirAll(filepath.Dir(f.path))
+	f.createdDirs = dirs
+	if err != nil {
+		return fmt.Errorf("unable to create directory: %v", err)
+	}
+	if f.plan.Current != nil {
+		f.backup = f.tmp + ".orig"
+		if err := moveFile(f.path, f.backup); err != nil {
+			f.backup = ""
+			return fmt.Errorf("unable to backup file: %v", err)
+		}
+	}
+	f.committed = true
+	if err := moveFile(f.tmp, f.path); err != nil {
+		return fmt.Errorf("unable to write file: %v", err)
+	}
+	return nil
+}
+
+// Restores original files in reverse order.
+func (t *Transaction) rollback() error {
+	var errs []error
+	for i := len(t.staged) - 1; i >= 0; i-- {
+		f := t.staged[i]
+		if f.committed {
+			if f.backup != "" {
+				if err := moveFile(f.backup, f.path); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %v", f.plan.Path, err))
+				}
+			} else if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("%s: %v", f.plan.Path, err))
+			}
+		}
+		for j := len(f.createdDirs) - 1; j >= 0; j-- {
+			// Directory may contain other files, so ignore error.
+			os.Remove(f.createdDirs[j])
+		}
+	}
+	return util.ComposeErrors(errs)
+}
+
+// Creates directory with all parents and returns created directories from top to bottom.
+func mkdirAll(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		missing = append([]string{d}, missing...)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	var created []string
+	for _, d := range missing {
+		if err := os.Mkdir(d, MkdirPermissions); err != nil && !os.IsExist(err) {
+			return created, err
+		}
+		created = append(created, d)
+	}
+	return created, nil
+}
+
+// Renames file or copies it, when rename is impossible (e.g. between devices).
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+	content, err := ioutil.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(to, content, 0644); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
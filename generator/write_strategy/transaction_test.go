@@ -0,0 +1,97 @@
+package write_strategy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "endpoints.go")
+	if err := ioutil.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// File with the same name as directory, so second file can not be written.
+	if err := ioutil.WriteFile(filepath.Join(dir, "transport"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := NewTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Close()
+	plans := []*Plan{
+		{Action: NewFileMark, Path: existing, Current: []byte("old"), Result: []byte("new")},
+		{Action: NewFileMark, Path: filepath.Join(dir, "middleware", "logging.go"), Result: []byte("logging")},
+		{Action: NewFileMark, Path: filepath.Join(dir, "transport", "http", "server.go"), Result: []byte("server")},
+	}
+	for _, plan := range plans {
+		if err := tx.Stage(plan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected commit error")
+	}
+
+	content, err := ioutil.ReadFile(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "old" {
+		t.Errorf("original file was not restored: %s", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "middleware")); !os.IsNotExist(err) {
+		t.Errorf("created directory was not removed: %v", err)
+	}
+}
+
+func TestTransactionRemoveRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	removed := filepath.Join(dir, "client.go")
+	if err := ioutil.WriteFile(removed, []byte("client"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "transport"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := NewTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Close()
+	plans := []*Plan{
+		{Action: RemoveFileMark, Path: removed, Current: []byte("client")},
+		{Action: NewFileMark, Path: filepath.Join(dir, "transport", "http", "server.go"), Result: []byte("server")},
+	}
+	for _, plan := range plans {
+		if err := tx.Stage(plan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected commit error")
+	}
+
+	content, err := ioutil.ReadFile(removed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "client" {
+		t.Errorf("removed file was not restored: %s", content)
+	}
+}
@@ -0,0 +1,36 @@
+package util
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		old, new string
+		diff     string
+	}{
+		{
+			old:  "a\nb\nc\n",
+			new:  "a\nb\nc\n",
+			diff: "",
+		},
+		{
+			old:  "",
+			new:  "a\nb\n",
+			diff: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			diff: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			diff: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+	}
+	for i, test := range tests {
+		if diff := UnifiedDiff("old", "new", []byte(test.old), []byte(test.new)); diff != test.diff {
+			t.Errorf("%d: got:\n%s\nexpected:\n%s", i+1, diff, test.diff)
+		}
+	}
+}
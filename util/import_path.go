@@ -0,0 +1,293 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const goModFileName = "go.mod"
+
+// Information from go.mod file, that needs for import paths resolution.
+type GoMod struct {
+	// Absolute path to directory, which contains go.mod file.
+	Dir string
+	// Path from `module` directive.
+	Module string
+	// Replace directives, which points to local directories.
+	Replaces []Replace
+}
+
+// Replace directive with local path.
+//
+//		replace github.com/user/lib => ../lib
+//
+type Replace struct {
+	// Replaced module path.
+	Old string
+	// Absolute path to replacement directory.
+	Dir string
+}
+
+// Looks for the nearest go.mod file in dir or its parents.
+// Returns nil without error if no go.mod file was found.
+func FindGoMod(dir string) (*GoMod, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		filename := filepath.Join(dir, goModFileName)
+		if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+			return ParseGoMod(filename)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Parses `module` and local `replace` directives of go.mod file.
+func ParseGoMod(filename string) (*GoMod, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mod := &GoMod{Dir: filepath.Dir(filename)}
+	inReplaceBlock := false
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripGoModComment(scanner.Text()))
+		if inReplaceBlock {
+			if text == ")" {
+				inReplaceBlock = false
+			} else {
+				mod.addReplace(text)
+			}
+			continue
+		}
+		directive, rest := splitGoModDirective(text)
+		switch directive {
+		case "module":
+			path, err := unquoteGoModPath(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+			}
+			mod.Module = path
+		case "replace":
+			if rest == "(" {
+				inReplaceBlock = true
+				continue
+			}
+			mod.addReplace(rest)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if mod.Module == "" {
+		return nil, fmt.Errorf("%s: module directive not found", filename)
+	}
+	return mod, nil
+}
+
+// Returns import path of package in dir, which should be inside module directory.
+// Local replace directives have priority over module path.
+func (m *GoMod) ImportPath(dir string) (string, bool) {
+	if path, ok := m.ReplacedImportPath(dir); ok {
+		return path, true
+	}
+	rel, ok := relativePath(m.Dir, dir)
+	if !ok {
+		return "", false
+	}
+	return joinImportPath(m.Module, rel), true
+}
+
+// Returns import path of package in dir, if dir is inside one of local replacements.
+// The longest replacement directory wins.
+func (m *GoMod) ReplacedImportPath(dir string) (string, bool) {
+	var best *Replace
+	var bestRel string
+	for i := range m.Replaces {
+		rel, ok := relativePath(m.Replaces[i].Dir, dir)
+		if ok && (best == nil || len(m.Replaces[i].Dir) > len(best.Dir)) {
+			best, bestRel = &m.Replaces[i], rel
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return joinImportPath(best.Old, bestRel), true
+}
+
+func (m *GoMod) addReplace(directive string) {
+	parts := strings.Split(directive, "=>")
+	if len(parts) != 2 {
+		return
+	}
+	oldFields, newFields := strings.Fields(parts[0]), strings.Fields(parts[1])
+	// Replacement with version is not a local path.
+	if len(oldFields) == 0 || len(newFields) != 1 {
+		return
+	}
+	oldPath, err := unquoteGoModPath(oldFields[0])
+	if err != nil {
+		return
+	}
+	newPath, err := unquoteGoModPath(newFields[0])
+	if err != nil || !isLocalGoModPath(newPath) {
+		return
+	}
+	if !filepath.IsAbs(newPath) {
+		newPath = filepath.Join(m.Dir, filepath.FromSlash(newPath))
+	}
+	m.Replaces = append(m.Replaces, Replace{Old: oldPath, Dir: filepath.Clean(newPath)})
+}
+
+// Same rules as go command uses: local paths start with ./ or ../ or are absolute.
+func isLocalGoModPath(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") ||
+		path == "." || path == ".." || filepath.IsAbs(path)
+}
+
+func splitGoModDirective(line string) (directive, rest string) {
+	i := strings.IndexAny(line, " \t(")
+	if i < 0 {
+		return line, ""
+	}
+	return line[:i], strings.TrimSpace(line[i:])
+}
+
+// Cuts comment from line of go.mod file. Slashes inside quoted strings are not comments.
+//
+//		module "example.com//svc" // comment -> module "example.com//svc"
+//
+func stripGoModComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case strings.HasPrefix(line[i:], "//"):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteGoModPath(path string) (string, error) {
+	if strings.HasPrefix(path, `"`) || strings.HasPrefix(path, "`") {
+		return strconv.Unquote(path)
+	}
+	if path == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	return path, nil
+}
+
+// Returns directory of package with import path, as it is seen from package in fromDir:
+// local module and its local replacements, vendor directory of module and GOPATH are checked.
+func PackageDir(importPath, fromDir string) (string, bool) {
+	mod, err := FindGoMod(fromDir)
+	if err == nil && mod != nil {
+		var candidates []string
+		for _, r := range mod.Replaces {
+			if rel, ok := trimImportPath(r.Old, importPath); ok {
+				candidates = append(candidates, filepath.Join(r.Dir, rel))
+			}
+		}
+		if rel, ok := trimImportPath(mod.Module, importPath); ok {
+			candidates = append(candidates, filepath.Join(mod.Dir, rel))
+		}
+		candidates = append(candidates, filepath.Join(mod.Dir, "vendor", filepath.FromSlash(importPath)))
+		for _, dir := range candidates {
+			if isDir(dir) {
+				return dir, true
+			}
+		}
+	}
+	for _, gopath := range filepath.SplitList(os.Getenv("GOPATH")) {
+		dir := filepath.Join(gopath, "src", filepath.FromSlash(importPath))
+		if gopath != "" && isDir(dir) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// Returns path of package relative to module, if package is inside module.
+func trimImportPath(module, importPath string) (string, bool) {
+	if importPath == module {
+		return "", true
+	}
+	if strings.HasPrefix(importPath, module+"/") {
+		return filepath.FromSlash(importPath[len(module)+1:]), true
+	}
+	return "", false
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Returns slash-separated path of target relative to base if target is inside base.
+func relativePath(base, target string) (string, bool) {
+	rel, err := filepath.Rel(base, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Returns import path of package in dir, if dir is inside src directory of gopath.
+func GopathImportPath(gopath, dir string) (string, bool) {
+	rel, ok := relativePath(filepath.Join(gopath, "src"), dir)
+	if !ok || rel == "." {
+		return "", false
+	}
+	if vendored, ok := trimVendor(rel); ok {
+		return vendored, true
+	}
+	return rel, true
+}
+
+// Joins import path with slash-separated relative path.
+// Packages inside vendor directory have import path relative to it.
+func joinImportPath(base, rel string) string {
+	if rel == "." {
+		return base
+	}
+	if vendored, ok := trimVendor(rel); ok {
+		return vendored
+	}
+	return base + "/" + rel
+}
+
+// Trims everything before the last vendor directory from slash-separated path.
+//
+//		github.com/user/project/vendor/github.com/user/lib -> github.com/user/lib
+//
+func trimVendor(path string) (string, bool) {
+	if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+		return path[i+len("/vendor/"):], true
+	}
+	if strings.HasPrefix(path, "vendor/") {
+		return path[len("vendor/"):], true
+	}
+	return path, false
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStripGoModComment(t *testing.T) {
+	for line, want := range map[string]string{
+		`module example.com/svc // comment`:       `module example.com/svc `,
+		`module "example.com//svc" // comment`:    `module "example.com//svc" `,
+		"module `example.com//svc`":               "module `example.com//svc`",
+		`module "example.com/\"//svc" // comment`: `module "example.com/\"//svc" `,
+		`// comment`: ``,
+	} {
+		if got := stripGoModComment(line); got != want {
+			t.Errorf("stripGoModComment(%q) = %q, expected %q", line, got, want)
+		}
+	}
+}
+
+func TestParseGoModQuotedPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content := "module \"example.com//svc\" // comment\n\nreplace \"example.com//lib\" => \"./lib\" // local\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mod, err := ParseGoMod(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.Module != "example.com//svc" {
+		t.Errorf("module is %q", mod.Module)
+	}
+	if len(mod.Replaces) != 1 || mod.Replaces[0].Old != "example.com//lib" || mod.Replaces[0].Dir != filepath.Join(dir, "lib") {
+		t.Errorf("unexpected replaces: %+v", mod.Replaces)
+	}
+}